@@ -0,0 +1,457 @@
+package kustomize
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	k8sadmissionv1 "k8s.io/api/admissionregistration/v1"
+	k8scorev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	plugincel "k8s.io/apiserver/pkg/admission/plugin/cel"
+	"k8s.io/apiserver/pkg/admission/plugin/policy/generic"
+	"k8s.io/apiserver/pkg/admission/plugin/policy/matching"
+	"k8s.io/apiserver/pkg/admission/plugin/policy/validating"
+	"k8s.io/apiserver/pkg/admission/plugin/webhook/matchconditions"
+	"k8s.io/apiserver/pkg/admission/plugin/webhook/predicates/rules"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+	"k8s.io/apiserver/pkg/cel/environment"
+	"sigs.k8s.io/kustomize/api/resmap"
+)
+
+func getAdmissionPoliciesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"policy_files": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"fail_on_violations": {
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  true,
+				},
+			},
+		},
+	}
+}
+
+// Evaluates ValidatingAdmissionPolicies offline, against the objects of a
+// build, using the API server's admission library. Objects are admitted as
+// if they were created. Without a cluster, resources are guessed from kinds
+// and namespaces outside the build are assumed to exist without labels.
+type admissionPolicyEvaluator struct {
+	policies   map[string]*k8sadmissionv1.ValidatingAdmissionPolicy
+	validators map[string]validating.Validator
+	bindings   []*k8sadmissionv1.ValidatingAdmissionPolicyBinding
+	objects    []*k8sunstructured.Unstructured
+	namespaces admissionNamespaceLister
+	matcher    generic.PolicyMatcher
+}
+
+func newAdmissionPolicyEvaluator() *admissionPolicyEvaluator {
+	e := &admissionPolicyEvaluator{
+		policies:   make(map[string]*k8sadmissionv1.ValidatingAdmissionPolicy),
+		validators: make(map[string]validating.Validator),
+		namespaces: make(admissionNamespaceLister),
+	}
+	e.matcher = generic.NewPolicyMatcher(matching.NewMatcher(e.namespaces, nil))
+
+	return e
+}
+
+// Adds an object, policies and bindings are also kept
+// separately, Namespaces are used to match namespaceSelectors.
+func (e *admissionPolicyEvaluator) add(j []byte) error {
+	u := &k8sunstructured.Unstructured{}
+	err := u.UnmarshalJSON(j)
+	if err != nil {
+		return err
+	}
+	e.objects = append(e.objects, u)
+
+	gvk := u.GroupVersionKind()
+	switch {
+	case gvk == k8sadmissionv1.SchemeGroupVersion.WithKind("ValidatingAdmissionPolicy"):
+		p := &k8sadmissionv1.ValidatingAdmissionPolicy{}
+		err = json.Unmarshal(j, p)
+		if err != nil {
+			return err
+		}
+		defaultMatchResources(p.Spec.MatchConstraints)
+		v, err := compileAdmissionPolicy(p)
+		if err != nil {
+			return fmt.Errorf("policy %q: %s", p.Name, err)
+		}
+		e.policies[p.Name] = p
+		e.validators[p.Name] = v
+	case gvk == k8sadmissionv1.SchemeGroupVersion.WithKind("ValidatingAdmissionPolicyBinding"):
+		b := &k8sadmissionv1.ValidatingAdmissionPolicyBinding{}
+		err = json.Unmarshal(j, b)
+		if err != nil {
+			return err
+		}
+		defaultMatchResources(b.Spec.MatchResources)
+		e.bindings = append(e.bindings, b)
+	case gvk.Group == "" && gvk.Kind == "Namespace":
+		ns := &k8scorev1.Namespace{}
+		err = json.Unmarshal(j, ns)
+		if err != nil {
+			return err
+		}
+		e.namespaces[ns.Name] = ns
+	}
+
+	return nil
+}
+
+// Adds a local file of YAML or JSON manifests, e.g. the
+// policies, bindings and params deployed separately.
+func (e *admissionPolicyEvaluator) addPolicyFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	docs, err := splitManifests(data)
+	if err != nil {
+		return fmt.Errorf("%q: %s", path, err)
+	}
+
+	for _, j := range docs {
+		err = e.add(j)
+		if err != nil {
+			return fmt.Errorf("%q: %s", path, err)
+		}
+	}
+
+	return nil
+}
+
+// The API server defaults empty selectors to match everything.
+func defaultMatchResources(mr *k8sadmissionv1.MatchResources) {
+	if mr == nil {
+		return
+	}
+	if mr.NamespaceSelector == nil {
+		mr.NamespaceSelector = &k8smetav1.LabelSelector{}
+	}
+	if mr.ObjectSelector == nil {
+		mr.ObjectSelector = &k8smetav1.LabelSelector{}
+	}
+}
+
+// Compiles a policy's expressions the way the ValidatingAdmissionPolicy
+// admission plugin does. Invalid expressions are reported per object,
+// subject to the policy's failurePolicy.
+func compileAdmissionPolicy(p *k8sadmissionv1.ValidatingAdmissionPolicy) (validating.Validator, error) {
+	hasParams := p.Spec.ParamKind != nil
+	optionalVars := plugincel.OptionalVariableDeclarations{HasParams: hasParams, HasAuthorizer: true}
+	messageVars := plugincel.OptionalVariableDeclarations{HasParams: hasParams, HasAuthorizer: false}
+
+	env, err := plugincel.NewCompositionEnv(plugincel.VariablesTypeName, environment.MustBaseEnvSet(environment.DefaultCompatibilityVersion()))
+	if err != nil {
+		return nil, err
+	}
+	compiler := plugincel.NewCompositedCompilerFromTemplate(env)
+
+	variables := make([]plugincel.NamedExpressionAccessor, len(p.Spec.Variables))
+	for i, v := range p.Spec.Variables {
+		variables[i] = &validating.Variable{Name: v.Name, Expression: v.Expression}
+	}
+	compiler.CompileAndStoreVariables(variables, optionalVars, environment.StoredExpressions)
+
+	var matcher matchconditions.Matcher
+	if len(p.Spec.MatchConditions) > 0 {
+		conditions := make([]plugincel.ExpressionAccessor, len(p.Spec.MatchConditions))
+		for i := range p.Spec.MatchConditions {
+			conditions[i] = (*matchconditions.MatchCondition)(&p.Spec.MatchConditions[i])
+		}
+		matcher = matchconditions.NewMatcher(compiler.CompileCondition(conditions, optionalVars, environment.StoredExpressions), p.Spec.FailurePolicy, "policy", "validate", p.Name)
+	}
+
+	validations := make([]plugincel.ExpressionAccessor, len(p.Spec.Validations))
+	messages := make([]plugincel.ExpressionAccessor, len(p.Spec.Validations))
+	for i, v := range p.Spec.Validations {
+		validations[i] = &validating.ValidationCondition{
+			Expression: v.Expression,
+			Message:    v.Message,
+			Reason:     v.Reason,
+		}
+		if v.MessageExpression != "" {
+			messages[i] = &validating.MessageExpressionCondition{MessageExpression: v.MessageExpression}
+		}
+	}
+
+	annotations := make([]plugincel.ExpressionAccessor, len(p.Spec.AuditAnnotations))
+	for i, a := range p.Spec.AuditAnnotations {
+		annotations[i] = &validating.AuditAnnotationCondition{Key: a.Key, ValueExpression: a.ValueExpression}
+	}
+
+	return validating.NewValidator(
+		compiler.CompileCondition(validations, optionalVars, environment.StoredExpressions),
+		matcher,
+		compiler.CompileCondition(annotations, optionalVars, environment.StoredExpressions),
+		compiler.CompileCondition(messages, messageVars, environment.StoredExpressions),
+		p.Spec.FailurePolicy,
+	), nil
+}
+
+// Evaluates every binding with the Deny action against the object,
+// returns the messages of the policies that deny it.
+func (e *admissionPolicyEvaluator) evaluate(u *k8sunstructured.Unstructured) (violations []string) {
+	gvk := u.GroupVersionKind()
+	gvr, _ := k8smeta.UnsafeGuessKindToResource(gvk)
+
+	ns := u.GetNamespace()
+	if gvk.Group == "" && gvk.Kind == "Namespace" {
+		ns = u.GetName()
+	}
+
+	attr := admission.NewAttributesRecord(u, nil, gvk, ns, u.GetName(), gvr, "", admission.Create, &k8smetav1.CreateOptions{}, false, nil)
+	if rules.IsExemptAdmissionConfigurationResource(attr) {
+		return nil
+	}
+
+	o := admission.NewObjectInterfacesFromScheme(k8sruntime.NewScheme())
+
+	var namespace *k8scorev1.Namespace
+	if u.GetNamespace() != "" {
+		namespace, _ = e.namespaces.Get(u.GetNamespace())
+	}
+
+	for _, b := range e.bindings {
+		if !containsString(validationActionsToStrings(b.Spec.ValidationActions), string(k8sadmissionv1.Deny)) {
+			continue
+		}
+
+		p, ok := e.policies[b.Spec.PolicyName]
+		if !ok {
+			continue
+		}
+
+		fail := func(msg string) {
+			if p.Spec.FailurePolicy != nil && *p.Spec.FailurePolicy == k8sadmissionv1.Ignore {
+				return
+			}
+			violations = append(violations, fmt.Sprintf("policy %q, binding %q: %s", p.Name, b.Name, msg))
+		}
+
+		matches, matchedGVR, matchedGVK, err := e.matcher.DefinitionMatches(attr, o, validating.NewValidatingAdmissionPolicyAccessor(p))
+		if err != nil {
+			fail(err.Error())
+			continue
+		}
+		if !matches {
+			continue
+		}
+
+		matches, err = e.matcher.BindingMatches(attr, o, validating.NewValidatingAdmissionPolicyBindingAccessor(b))
+		if err != nil {
+			fail(err.Error())
+			continue
+		}
+		if !matches {
+			continue
+		}
+
+		params, err := e.getParams(p, b, u)
+		if err != nil {
+			fail(err.Error())
+			continue
+		}
+
+		versionedAttr := &admission.VersionedAttributes{
+			Attributes:      attr,
+			VersionedKind:   matchedGVK,
+			VersionedObject: u,
+		}
+
+		for _, param := range params {
+			result := e.validators[p.Name].Validate(context.TODO(), matchedGVR, versionedAttr, param, namespace, celconfig.RuntimeCELCostBudget, nil)
+			for _, decision := range result.Decisions {
+				if decision.Action == validating.ActionDeny {
+					violations = append(violations, fmt.Sprintf("policy %q, binding %q: %s", p.Name, b.Name, decision.Message))
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+// Returns the params to evaluate the policy with, a single nil entry if
+// the policy has no paramKind and none if missing params are allowed.
+func (e *admissionPolicyEvaluator) getParams(p *k8sadmissionv1.ValidatingAdmissionPolicy, b *k8sadmissionv1.ValidatingAdmissionPolicyBinding, u *k8sunstructured.Unstructured) ([]k8sruntime.Object, error) {
+	if p.Spec.ParamKind == nil {
+		return []k8sruntime.Object{nil}, nil
+	}
+
+	ref := b.Spec.ParamRef
+	if ref == nil {
+		return nil, fmt.Errorf("policy has paramKind, but binding has no paramRef")
+	}
+
+	gv, err := k8sschema.ParseGroupVersion(p.Spec.ParamKind.APIVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid paramKind: %s", err)
+	}
+
+	selector := k8slabels.Everything()
+	if ref.Selector != nil {
+		selector, err = k8smetav1.LabelSelectorAsSelector(ref.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid paramRef selector: %s", err)
+		}
+	}
+
+	var params []k8sruntime.Object
+	for _, o := range e.objects {
+		if o.GroupVersionKind().GroupKind() != gv.WithKind(p.Spec.ParamKind.Kind).GroupKind() {
+			continue
+		}
+
+		// the scope of the param kind is unknown without a cluster
+		if ref.Namespace != "" && o.GetNamespace() != ref.Namespace {
+			continue
+		}
+		if ref.Namespace == "" && o.GetNamespace() != "" && o.GetNamespace() != u.GetNamespace() {
+			continue
+		}
+
+		if ref.Name != "" && o.GetName() != ref.Name {
+			continue
+		}
+		if ref.Name == "" && !selector.Matches(k8slabels.Set(o.GetLabels())) {
+			continue
+		}
+
+		params = append(params, o)
+	}
+
+	if len(params) == 0 {
+		if ref.ParameterNotFoundAction != nil && *ref.ParameterNotFoundAction == k8sadmissionv1.AllowAction {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("no params found for paramRef")
+	}
+
+	return params, nil
+}
+
+func validationActionsToStrings(actions []k8sadmissionv1.ValidationAction) (s []string) {
+	for _, a := range actions {
+		s = append(s, string(a))
+	}
+	return s
+}
+
+// Lists the Namespaces of the build, instead of the cluster's.
+type admissionNamespaceLister map[string]*k8scorev1.Namespace
+
+func (l admissionNamespaceLister) List(selector k8slabels.Selector) (ret []*k8scorev1.Namespace, err error) {
+	for _, ns := range l {
+		if selector.Matches(k8slabels.Set(ns.Labels)) {
+			ret = append(ret, ns)
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+
+	return ret, nil
+}
+
+func (l admissionNamespaceLister) Get(name string) (*k8scorev1.Namespace, error) {
+	if name == "" {
+		return nil, k8serrors.NewNotFound(k8scorev1.Resource("namespaces"), name)
+	}
+
+	ns, ok := l[name]
+	if !ok {
+		return &k8scorev1.Namespace{ObjectMeta: k8smetav1.ObjectMeta{Name: name}}, nil
+	}
+
+	return ns, nil
+}
+
+// Sets admission_policy_violations, if admission_policies is set, from
+// evaluating the ValidatingAdmissionPolicies of the build and the policy
+// files against the resources of the build.
+func setAdmissionPolicyViolations(d *schema.ResourceData, rm resmap.ResMap) error {
+	opts := getBlockOptions(d, "admission_policies")
+	if opts == nil {
+		d.Set("admission_policy_violations", map[string]string{})
+		return nil
+	}
+
+	violations, err := getAdmissionPolicyViolations(rm, opts)
+	if err != nil {
+		return fmt.Errorf("admission_policies: %s", err)
+	}
+
+	err = setProblems(d, "admission_policy_violations", violations, opts["fail_on_violations"].(bool))
+	if err != nil {
+		return fmt.Errorf("admission_policies: %s", err)
+	}
+
+	return nil
+}
+
+func getAdmissionPolicyViolations(rm resmap.ResMap, opts map[string]interface{}) (map[string][]string, error) {
+	e := newAdmissionPolicyEvaluator()
+
+	ids := make(map[*k8sunstructured.Unstructured]string)
+	for _, r := range rm.Resources() {
+		j, err := r.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		id := getResourceID(r).string()
+		err = e.add(j)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", id, err)
+		}
+		ids[e.objects[len(e.objects)-1]] = id
+	}
+
+	for _, f := range opts["policy_files"].([]interface{}) {
+		err := e.addPolicyFile(f.(string))
+		if err != nil {
+			return nil, fmt.Errorf("policy_files: %s", err)
+		}
+	}
+
+	violations := make(map[string][]string)
+	for _, u := range e.objects {
+		id, ok := ids[u]
+		if !ok {
+			continue
+		}
+
+		msgs := e.evaluate(u)
+		if len(msgs) > 0 {
+			violations[id] = msgs
+		}
+	}
+
+	return violations, nil
+}
@@ -0,0 +1,61 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func getAdmissionPoliciesTestData(t *testing.T, opts map[string]interface{}) *schema.ResourceData {
+	return getBuildTestData(t, "test_kustomizations/admission_policies", map[string]interface{}{
+		"admission_policies": []interface{}{opts},
+	})
+}
+
+func TestAdmissionPolicies(t *testing.T) {
+	d := getAdmissionPoliciesTestData(t, map[string]interface{}{
+		"fail_on_violations": false,
+	})
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, map[string][]string{
+		"apps/Deployment/prod/test": []string{
+			`policy "max-replicas", binding "max-replicas-prod": replicas must be at most 5, got 10`,
+		},
+	}, getBuildTestJSONLists(t, d, "admission_policy_violations"), nil)
+}
+
+func TestAdmissionPoliciesFailOnViolations(t *testing.T) {
+	d := getAdmissionPoliciesTestData(t, map[string]interface{}{})
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Equal(t, "admission_policies: 1 problem(s) found:\n"+
+		`"apps/Deployment/prod/test": policy "max-replicas", binding "max-replicas-prod": replicas must be at most 5, got 10`, err.Error(), nil)
+}
+
+func TestAdmissionPoliciesPolicyFiles(t *testing.T) {
+	d := getAdmissionPoliciesTestData(t, map[string]interface{}{
+		"policy_files":       []interface{}{"test_kustomizations/_test_files/admission_policies.yaml"},
+		"fail_on_violations": false,
+	})
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+
+	violations := getBuildTestJSONLists(t, d, "admission_policy_violations")
+	assert.Equal(t, 3, len(violations), nil)
+	assert.Equal(t, []string{
+		`policy "required-label", binding "required-label": missing required label`,
+	}, violations["apps/Deployment/dev/test"], nil)
+}
+
+func TestAdmissionPoliciesDisabled(t *testing.T) {
+	d := getBuildTestData(t, "test_kustomizations/admission_policies", nil)
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, map[string]interface{}{}, d.Get("admission_policy_violations"), nil)
+}
@@ -0,0 +1,214 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	k8sversion "k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/kustomize/api/resmap"
+)
+
+// An API version of a kind that upstream Kubernetes deprecated or removed.
+type apiDeprecation struct {
+	groupVersion string
+	kinds        []string
+	deprecatedIn string
+	removedIn    string
+	// empty if the kind has no replacement
+	replacement string
+}
+
+// Upstream deprecations, from the Kubernetes deprecated API migration guide.
+var apiDeprecations = []apiDeprecation{
+	// removed in v1.16
+	{"extensions/v1beta1", []string{"Deployment", "DaemonSet", "ReplicaSet"}, "1.9", "1.16", "apps/v1"},
+	{"extensions/v1beta1", []string{"NetworkPolicy"}, "1.9", "1.16", "networking.k8s.io/v1"},
+	{"extensions/v1beta1", []string{"PodSecurityPolicy"}, "1.10", "1.16", "policy/v1beta1"},
+	{"apps/v1beta1", []string{"Deployment", "StatefulSet", "ControllerRevision"}, "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta2", []string{"Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ControllerRevision"}, "1.9", "1.16", "apps/v1"},
+
+	// removed in v1.22
+	{"admissionregistration.k8s.io/v1beta1", []string{"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration"}, "1.16", "1.22", "admissionregistration.k8s.io/v1"},
+	{"apiextensions.k8s.io/v1beta1", []string{"CustomResourceDefinition"}, "1.16", "1.22", "apiextensions.k8s.io/v1"},
+	{"apiregistration.k8s.io/v1beta1", []string{"APIService"}, "1.19", "1.22", "apiregistration.k8s.io/v1"},
+	{"authentication.k8s.io/v1beta1", []string{"TokenReview"}, "1.19", "1.22", "authentication.k8s.io/v1"},
+	{"authorization.k8s.io/v1beta1", []string{"SubjectAccessReview", "LocalSubjectAccessReview", "SelfSubjectAccessReview", "SelfSubjectRulesReview"}, "1.19", "1.22", "authorization.k8s.io/v1"},
+	{"certificates.k8s.io/v1beta1", []string{"CertificateSigningRequest"}, "1.19", "1.22", "certificates.k8s.io/v1"},
+	{"coordination.k8s.io/v1beta1", []string{"Lease"}, "1.19", "1.22", "coordination.k8s.io/v1"},
+	{"extensions/v1beta1", []string{"Ingress"}, "1.14", "1.22", "networking.k8s.io/v1"},
+	{"networking.k8s.io/v1beta1", []string{"Ingress", "IngressClass"}, "1.19", "1.22", "networking.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", []string{"ClusterRole", "ClusterRoleBinding", "Role", "RoleBinding"}, "1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"scheduling.k8s.io/v1beta1", []string{"PriorityClass"}, "1.14", "1.22", "scheduling.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", []string{"CSIDriver", "CSINode", "StorageClass", "VolumeAttachment"}, "1.19", "1.22", "storage.k8s.io/v1"},
+
+	// removed in v1.25
+	{"batch/v1beta1", []string{"CronJob"}, "1.21", "1.25", "batch/v1"},
+	{"discovery.k8s.io/v1beta1", []string{"EndpointSlice"}, "1.21", "1.25", "discovery.k8s.io/v1"},
+	{"events.k8s.io/v1beta1", []string{"Event"}, "1.19", "1.25", "events.k8s.io/v1"},
+	{"autoscaling/v2beta1", []string{"HorizontalPodAutoscaler"}, "1.23", "1.25", "autoscaling/v2"},
+	{"policy/v1beta1", []string{"PodDisruptionBudget"}, "1.21", "1.25", "policy/v1"},
+	{"policy/v1beta1", []string{"PodSecurityPolicy"}, "1.21", "1.25", ""},
+	{"node.k8s.io/v1beta1", []string{"RuntimeClass"}, "1.20", "1.25", "node.k8s.io/v1"},
+
+	// removed in v1.26
+	{"flowcontrol.apiserver.k8s.io/v1beta1", []string{"FlowSchema", "PriorityLevelConfiguration"}, "1.23", "1.26", "flowcontrol.apiserver.k8s.io/v1beta3"},
+	{"autoscaling/v2beta2", []string{"HorizontalPodAutoscaler"}, "1.23", "1.26", "autoscaling/v2"},
+
+	// removed in v1.27
+	{"storage.k8s.io/v1beta1", []string{"CSIStorageCapacity"}, "1.24", "1.27", "storage.k8s.io/v1"},
+
+	// removed in v1.29
+	{"flowcontrol.apiserver.k8s.io/v1beta2", []string{"FlowSchema", "PriorityLevelConfiguration"}, "1.26", "1.29", "flowcontrol.apiserver.k8s.io/v1"},
+
+	// removed in v1.32
+	{"flowcontrol.apiserver.k8s.io/v1beta3", []string{"FlowSchema", "PriorityLevelConfiguration"}, "1.29", "1.32", "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+func getTargetKubeVersionSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		ValidateFunc: validateKubeVersion,
+	}
+}
+
+func validateKubeVersion(v interface{}, k string) (ws []string, es []error) {
+	_, err := k8sversion.ParseGeneric(v.(string))
+	if err != nil {
+		es = append(es, fmt.Errorf("%s: %s", k, err))
+	}
+
+	return ws, es
+}
+
+func findAPIDeprecation(gvk k8sschema.GroupVersionKind) *apiDeprecation {
+	for i := range apiDeprecations {
+		dep := &apiDeprecations[i]
+		if dep.groupVersion != gvk.GroupVersion().String() {
+			continue
+		}
+
+		for _, kind := range dep.kinds {
+			if kind == gvk.Kind {
+				return dep
+			}
+		}
+	}
+
+	return nil
+}
+
+func (dep *apiDeprecation) message(gvk k8sschema.GroupVersionKind, removed bool) string {
+	var msg string
+	if removed {
+		msg = fmt.Sprintf("%s %s was removed in Kubernetes v%s", dep.groupVersion, gvk.Kind, dep.removedIn)
+	} else {
+		msg = fmt.Sprintf("%s %s is deprecated since Kubernetes v%s and will be removed in v%s", dep.groupVersion, gvk.Kind, dep.deprecatedIn, dep.removedIn)
+	}
+
+	if dep.replacement == "" {
+		return fmt.Sprintf("%s, there is no replacement", msg)
+	}
+
+	return fmt.Sprintf("%s, use %s instead", msg, dep.replacement)
+}
+
+// Finds resources using API versions deprecated or removed in
+// the target version, removed API versions are errors.
+func getAPIDeprecations(rm resmap.ResMap, target string) (deprecated map[string]string, removed map[string]string, err error) {
+	tv, err := k8sversion.ParseGeneric(target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deprecated = make(map[string]string)
+	removed = make(map[string]string)
+	for _, r := range rm.Resources() {
+		gvk := k8sschema.FromAPIVersionAndKind(r.GetApiVersion(), r.GetKind())
+
+		dep := findAPIDeprecation(gvk)
+		if dep == nil {
+			continue
+		}
+
+		id := getResourceID(r).string()
+		switch {
+		case tv.AtLeast(k8sversion.MustParseGeneric(dep.removedIn)):
+			removed[id] = dep.message(gvk, true)
+		case tv.AtLeast(k8sversion.MustParseGeneric(dep.deprecatedIn)):
+			deprecated[id] = dep.message(gvk, false)
+		}
+	}
+
+	return deprecated, removed, nil
+}
+
+// Sets api_deprecations, if target_kube_version is set. Resources
+// using removed API versions fail the read.
+func setAPIDeprecations(d *schema.ResourceData, rm resmap.ResMap) error {
+	target := d.Get("target_kube_version").(string)
+	if target == "" {
+		d.Set("api_deprecations", map[string]string{})
+		return nil
+	}
+
+	deprecated, removed, err := getAPIDeprecations(rm, target)
+	if err != nil {
+		return fmt.Errorf("target_kube_version: %s", err)
+	}
+
+	all := make(map[string]string)
+	for id, msg := range deprecated {
+		all[id] = msg
+	}
+	for id, msg := range removed {
+		all[id] = msg
+	}
+	d.Set("api_deprecations", all)
+
+	if len(removed) > 0 {
+		problems := make(map[string][]string)
+		for id, msg := range removed {
+			problems[id] = []string{msg}
+		}
+
+		return fmt.Errorf("target_kube_version: %s", fmtProblems(problems))
+	}
+
+	return nil
+}
+
+// Wraps a data source's read function, to warn about
+// the deprecated API versions it found.
+func readWithAPIDeprecationWarnings(read func(*schema.ResourceData, interface{}) error) schema.ReadContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		err := read(d, m)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		deprecations, _ := d.Get("api_deprecations").(map[string]interface{})
+
+		ids := make([]string, 0, len(deprecations))
+		for id := range deprecations {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		var diags diag.Diagnostics
+		for _, id := range ids {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "Deprecated API version",
+				Detail:   fmt.Sprintf("%q: %s", id, deprecations[id]),
+			})
+		}
+
+		return diags
+	}
+}
@@ -0,0 +1,94 @@
+package kustomize
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+const apiDeprecationsTestResources = `
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: test
+  namespace: test
+---
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: test
+---
+apiVersion: autoscaling/v2beta2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: test
+  namespace: test
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  namespace: test
+`
+
+func getAPIDeprecationsTestData(t *testing.T, target string) *schema.ResourceData {
+	return schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+		"files": map[string]interface{}{
+			"kustomization.yaml": "resources:\n- resources.yaml\n",
+			"resources.yaml":     apiDeprecationsTestResources,
+		},
+		"target_kube_version": target,
+	})
+}
+
+func TestAPIDeprecations(t *testing.T) {
+	d := getAPIDeprecationsTestData(t, "v1.24.3")
+
+	diags := readWithAPIDeprecationWarnings(kustomizationBuild)(context.Background(), d, &Config{})
+	assert.Equal(t, diag.Diagnostics{
+		{
+			Severity: diag.Warning,
+			Summary:  "Deprecated API version",
+			Detail:   `"autoscaling/HorizontalPodAutoscaler/test/test": autoscaling/v2beta2 HorizontalPodAutoscaler is deprecated since Kubernetes v1.23 and will be removed in v1.26, use autoscaling/v2 instead`,
+		},
+		{
+			Severity: diag.Warning,
+			Summary:  "Deprecated API version",
+			Detail:   `"batch/CronJob/test/test": batch/v1beta1 CronJob is deprecated since Kubernetes v1.21 and will be removed in v1.25, use batch/v1 instead`,
+		},
+		{
+			Severity: diag.Warning,
+			Summary:  "Deprecated API version",
+			Detail:   `"policy/PodSecurityPolicy/_/test": policy/v1beta1 PodSecurityPolicy is deprecated since Kubernetes v1.21 and will be removed in v1.25, there is no replacement`,
+		},
+	}, diags, nil)
+	assert.Equal(t, 3, len(d.Get("api_deprecations").(map[string]interface{})), nil)
+}
+
+func TestAPIDeprecationsRemoved(t *testing.T) {
+	d := getAPIDeprecationsTestData(t, "1.25")
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Equal(t, `target_kube_version: 2 problem(s) found:
+"batch/CronJob/test/test": batch/v1beta1 CronJob was removed in Kubernetes v1.25, use batch/v1 instead
+"policy/PodSecurityPolicy/_/test": policy/v1beta1 PodSecurityPolicy was removed in Kubernetes v1.25, there is no replacement`, err.Error(), nil)
+}
+
+func TestAPIDeprecationsDisabled(t *testing.T) {
+	d := getAPIDeprecationsTestData(t, "")
+
+	diags := readWithAPIDeprecationWarnings(kustomizationBuild)(context.Background(), d, &Config{})
+	assert.Equal(t, diag.Diagnostics(nil), diags, nil)
+	assert.Equal(t, map[string]interface{}{}, d.Get("api_deprecations"), nil)
+}
+
+func TestValidateKubeVersion(t *testing.T) {
+	_, es := validateKubeVersion("v1.29", "target_kube_version")
+	assert.Equal(t, 0, len(es), nil)
+
+	_, es = validateKubeVersion("latest", "target_kube_version")
+	assert.Equal(t, 1, len(es), nil)
+}
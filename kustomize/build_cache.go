@@ -0,0 +1,385 @@
+package kustomize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/kustomize/api/provider"
+	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+const buildCacheInputsExt = ".inputs"
+const buildCacheResultExt = ".yaml"
+
+// On-disk cache for kustomize builds.
+//
+// Results are content addressed. The key is a hash of the build path,
+// the kustomize options, the cache_key argument, the data source's own
+// inputs, e.g. the overlay's Kustomization, and the content of every
+// file kustomize read and every path it checked during the build. Because the files a build reads
+// are only known after running it, the list of inputs of the last build
+// is stored under a hash of everything but the file contents.
+type buildCache struct {
+	dir     string
+	maxSize int64
+	mu      sync.Mutex
+}
+
+type buildCacheInput struct {
+	Op   string `json:"op"`
+	Name string `json:"name"`
+}
+
+func newBuildCache(dir string, maxSizeMB int) (*buildCache, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &buildCache{
+		dir:     dir,
+		maxSize: int64(maxSizeMB) * 1024 * 1024,
+	}, nil
+}
+
+func runCachedKustomizeBuild(c *buildCache, fSys filesys.FileSystem, path string, kOpts map[string]interface{}, salt ...string) (rm resmap.ResMap, err error) {
+	if c == nil || !isCacheableBuild(fSys, path, kOpts) {
+		return runKustomizeBuild(fSys, path, kOpts)
+	}
+
+	prefix, err := c.prefix(path, kOpts, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	rm, err = c.get(fSys, prefix)
+	if err == nil && rm != nil {
+		return rm, nil
+	}
+
+	rfs := &recordingFileSystem{FileSystem: fSys}
+	rm, err = runKustomizeBuild(rfs, path, kOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	// entries are plaintext, don't write secret data to disk
+	if hasSecrets(rm) {
+		return rm, nil
+	}
+
+	// failing to write the cache must never fail the build
+	c.put(fSys, prefix, rfs.inputs(), rm)
+
+	return rm, nil
+}
+
+// Helm charts, exec and KRM function plugins and remote resources are
+// read outside of fSys, so the cache can't tell when they change.
+func isCacheableBuild(fSys filesys.FileSystem, path string, kOpts map[string]interface{}) bool {
+	opts := getKustomizeOptions(kOpts)
+	if opts.PluginConfig.PluginRestrictions != types.PluginRestrictionsBuiltinsOnly {
+		return false
+	}
+
+	return !loadsRemoteKustomizations(fSys, path)
+}
+
+func hasSecrets(rm resmap.ResMap) bool {
+	for _, r := range rm.Resources() {
+		if r.CurId().Group == "" && r.GetKind() == "Secret" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *buildCache) prefix(path string, kOpts map[string]interface{}, salt []string) (string, error) {
+	opts, err := json.Marshal(getKustomizeOptions(kOpts))
+	if err != nil {
+		return "", err
+	}
+
+	bm, err := json.Marshal(getBuildMetadata(kOpts))
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, s := range append([]string{kustomizeVersion(), path, string(opts), string(bm)}, salt...) {
+		fmt.Fprintf(h, "%d:%s\n", len(s), s)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *buildCache) key(fSys filesys.FileSystem, prefix string, inputs []buildCacheInput) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", prefix)
+
+	for _, in := range inputs {
+		var content string
+		switch in.Op {
+		case "file":
+			b, err := fSys.ReadFile(in.Name)
+			if err != nil {
+				content = "!missing"
+			} else {
+				sum := sha256.Sum256(b)
+				content = hex.EncodeToString(sum[:])
+			}
+		case "dir":
+			n, err := fSys.ReadDir(in.Name)
+			if err != nil {
+				content = "!missing"
+			} else {
+				content = strings.Join(n, "\n")
+			}
+		case "exists":
+			switch {
+			case fSys.IsDir(in.Name):
+				content = "dir"
+			case fSys.Exists(in.Name):
+				content = "file"
+			default:
+				content = "!missing"
+			}
+		case "glob":
+			n, err := fSys.Glob(in.Name)
+			if err != nil {
+				content = "!missing"
+			} else {
+				content = strings.Join(n, "\n")
+			}
+		}
+
+		fmt.Fprintf(h, "%s:%d:%s:%d:%s\n", in.Op, len(in.Name), in.Name, len(content), content)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *buildCache) get(fSys filesys.FileSystem, prefix string) (resmap.ResMap, error) {
+	data, err := ioutil.ReadFile(filepath.Join(c.dir, prefix+buildCacheInputsExt))
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []buildCacheInput
+	err = json.Unmarshal(data, &inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	p := filepath.Join(c.dir, c.key(fSys, prefix, inputs)+buildCacheResultExt)
+	data, err = ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// keep recently used entries when pruning
+	now := time.Now()
+	os.Chtimes(p, now, now)
+
+	rmF := resmap.NewFactory(provider.NewDefaultDepProvider().GetResourceFactory())
+	return rmF.NewResMapFromBytes(data)
+}
+
+func (c *buildCache) put(fSys filesys.FileSystem, prefix string, inputs []buildCacheInput, rm resmap.ResMap) error {
+	data, err := rm.AsYaml()
+	if err != nil {
+		return err
+	}
+
+	in, err := json.Marshal(inputs)
+	if err != nil {
+		return err
+	}
+
+	key := c.key(fSys, prefix, inputs)
+
+	err = c.writeFile(key+buildCacheResultExt, data)
+	if err != nil {
+		return err
+	}
+
+	err = c.writeFile(prefix+buildCacheInputsExt, in)
+	if err != nil {
+		return err
+	}
+
+	return c.prune()
+}
+
+// Write to a temp file and rename, so parallel
+// builds never read partially written entries.
+func (c *buildCache) writeFile(name string, data []byte) error {
+	f, err := ioutil.TempFile(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(c.dir, name))
+}
+
+// Removes the least recently used entries until
+// the cache is smaller than its max size.
+func (c *buildCache) prune() error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fis, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	var entries []os.FileInfo
+	for _, fi := range fis {
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".tmp-") {
+			continue
+		}
+		size += fi.Size()
+		entries = append(entries, fi)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+
+	for _, fi := range entries {
+		if size <= c.maxSize {
+			break
+		}
+
+		err = os.Remove(filepath.Join(c.dir, fi.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		size -= fi.Size()
+	}
+
+	return nil
+}
+
+func kustomizeVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	for _, dep := range bi.Deps {
+		if dep.Path == "sigs.k8s.io/kustomize/api" {
+			return dep.Version
+		}
+	}
+
+	return "unknown"
+}
+
+var _ filesys.FileSystem = &recordingFileSystem{}
+
+// Records the files and directories a build reads and the paths
+// it checks, to compute the cache key from their content.
+type recordingFileSystem struct {
+	filesys.FileSystem
+
+	mu   sync.Mutex
+	seen map[buildCacheInput]bool
+}
+
+func (rfs *recordingFileSystem) record(op, name string) {
+	rfs.mu.Lock()
+	defer rfs.mu.Unlock()
+
+	if rfs.seen == nil {
+		rfs.seen = make(map[buildCacheInput]bool)
+	}
+	rfs.seen[buildCacheInput{Op: op, Name: name}] = true
+}
+
+func (rfs *recordingFileSystem) inputs() (r []buildCacheInput) {
+	rfs.mu.Lock()
+	defer rfs.mu.Unlock()
+
+	for in := range rfs.seen {
+		r = append(r, in)
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Name == r[j].Name {
+			return r[i].Op < r[j].Op
+		}
+		return r[i].Name < r[j].Name
+	})
+
+	return r
+}
+
+func (rfs *recordingFileSystem) ReadFile(name string) ([]byte, error) {
+	rfs.record("file", name)
+	return rfs.FileSystem.ReadFile(name)
+}
+
+func (rfs *recordingFileSystem) Open(name string) (filesys.File, error) {
+	rfs.record("file", name)
+	return rfs.FileSystem.Open(name)
+}
+
+func (rfs *recordingFileSystem) ReadDir(name string) ([]string, error) {
+	rfs.record("dir", name)
+	return rfs.FileSystem.ReadDir(name)
+}
+
+func (rfs *recordingFileSystem) Glob(pattern string) ([]string, error) {
+	rfs.record("glob", pattern)
+	return rfs.FileSystem.Glob(pattern)
+}
+
+func (rfs *recordingFileSystem) Exists(name string) bool {
+	rfs.record("exists", name)
+	return rfs.FileSystem.Exists(name)
+}
+
+func (rfs *recordingFileSystem) IsDir(name string) bool {
+	rfs.record("exists", name)
+	return rfs.FileSystem.IsDir(name)
+}
+
+func (rfs *recordingFileSystem) CleanedAbs(path string) (filesys.ConfirmedDir, string, error) {
+	rfs.record("exists", path)
+	return rfs.FileSystem.CleanedAbs(path)
+}
@@ -0,0 +1,158 @@
+package kustomize
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+func writeBuildCacheTestKustomization(t *testing.T, dir string, ns string) {
+	err := ioutil.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte("resources:\n- ns.yaml\n"), 0600)
+	assert.Equal(t, nil, err, nil)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "ns.yaml"), []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: "+ns+"\n"), 0600)
+	assert.Equal(t, nil, err, nil)
+}
+
+func getBuildCacheTestIDs(t *testing.T, c *buildCache, path string, cacheKey string) []string {
+	d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+		"path":      path,
+		"cache_key": cacheKey,
+	})
+
+	kOpts := getMergedKustomizeOptions(d, nil)
+	rm, err := runCachedKustomizeBuild(c, filesys.MakeFsOnDisk(), path, kOpts, d.Get("cache_key").(string))
+	assert.Equal(t, nil, err, nil)
+
+	var ids []string
+	for _, id := range rm.AllIds() {
+		ids = append(ids, id.Name)
+	}
+
+	return ids
+}
+
+func TestBuildCache(t *testing.T) {
+	kdir := t.TempDir()
+	cdir := t.TempDir()
+
+	c, err := newBuildCache(cdir, 1)
+	assert.Equal(t, nil, err, nil)
+
+	writeBuildCacheTestKustomization(t, kdir, "test-initial")
+	assert.Equal(t, []string{"test-initial"}, getBuildCacheTestIDs(t, c, kdir, ""), nil)
+
+	// replace the cached result, to tell hits from misses
+	results, _ := filepath.Glob(filepath.Join(cdir, "*"+buildCacheResultExt))
+	assert.Equal(t, 1, len(results), nil)
+	err = ioutil.WriteFile(results[0], []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: test-cached\n"), 0600)
+	assert.Equal(t, nil, err, nil)
+
+	// unchanged inputs are a hit
+	assert.Equal(t, []string{"test-cached"}, getBuildCacheTestIDs(t, c, kdir, ""), nil)
+
+	// a different cache_key is a miss
+	assert.Equal(t, []string{"test-initial"}, getBuildCacheTestIDs(t, c, kdir, "invalidate"), nil)
+
+	// changed input files are a miss
+	writeBuildCacheTestKustomization(t, kdir, "test-modified")
+	assert.Equal(t, []string{"test-modified"}, getBuildCacheTestIDs(t, c, kdir, ""), nil)
+}
+
+func TestBuildCacheSecrets(t *testing.T) {
+	kdir := t.TempDir()
+	cdir := t.TempDir()
+
+	c, err := newBuildCache(cdir, 1)
+	assert.Equal(t, nil, err, nil)
+
+	err = ioutil.WriteFile(filepath.Join(kdir, "kustomization.yaml"), []byte("secretGenerator:\n- name: test\n  literals:\n  - password=secret\n"), 0600)
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, 1, len(getBuildCacheTestIDs(t, c, kdir, "")), nil)
+
+	// builds with secrets are not written to disk
+	entries, err := ioutil.ReadDir(cdir)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 0, len(entries), nil)
+}
+
+func TestBuildCacheKeyExists(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	fSys.MkdirAll("base")
+
+	rfs := &recordingFileSystem{FileSystem: fSys}
+	rfs.Exists("kustomization.yml")
+	rfs.IsDir("base")
+	rfs.CleanedAbs("base")
+	assert.Equal(t, []buildCacheInput{
+		{Op: "exists", Name: "base"},
+		{Op: "exists", Name: "kustomization.yml"},
+	}, rfs.inputs(), nil)
+
+	c := &buildCache{}
+	key := c.key(fSys, "prefix", rfs.inputs())
+	assert.Equal(t, key, c.key(fSys, "prefix", rfs.inputs()), nil)
+
+	// files that were only checked still change the key
+	fSys.WriteFile("kustomization.yml", []byte("resources: []\n"))
+	assert.NotEqual(t, key, c.key(fSys, "prefix", rfs.inputs()), nil)
+}
+
+func TestBuildCacheDisabled(t *testing.T) {
+	c, err := newBuildCache("", 1)
+	assert.Equal(t, (*buildCache)(nil), c, nil)
+	assert.Equal(t, nil, err, nil)
+
+	kdir := t.TempDir()
+	writeBuildCacheTestKustomization(t, kdir, "test-initial")
+	assert.Equal(t, []string{"test-initial"}, getBuildCacheTestIDs(t, c, kdir, ""), nil)
+}
+
+func TestBuildCachePrune(t *testing.T) {
+	cdir := t.TempDir()
+	c := &buildCache{dir: cdir, maxSize: 10}
+
+	for i, n := range []string{"oldest", "older", "newest"} {
+		p := filepath.Join(cdir, n)
+		err := ioutil.WriteFile(p, []byte("12345"), 0600)
+		assert.Equal(t, nil, err, nil)
+
+		mt := time.Now().Add(time.Duration(i-3) * time.Hour)
+		os.Chtimes(p, mt, mt)
+	}
+
+	err := c.prune()
+	assert.Equal(t, nil, err, nil)
+
+	fis, err := ioutil.ReadDir(cdir)
+	assert.Equal(t, nil, err, nil)
+
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	assert.ElementsMatch(t, []string{"older", "newest"}, names, nil)
+}
+
+func TestIsCacheableBuild(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	fSys.WriteFile("local/kustomization.yaml", []byte("resources:\n- ns.yaml\n- base\n"))
+	fSys.WriteFile("local/ns.yaml", []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: test\n"))
+	fSys.WriteFile("local/base/kustomization.yaml", []byte("resources: []\n"))
+	fSys.WriteFile("remote/kustomization.yaml", []byte("resources:\n- base\n"))
+	fSys.WriteFile("remote/base/kustomization.yaml", []byte("resources:\n- https://github.com/kbst/terraform-provider-kustomization//kustomize/test_kustomizations/basic/initial\n"))
+
+	assert.Equal(t, true, isCacheableBuild(fSys, "local", nil), nil)
+	assert.Equal(t, false, isCacheableBuild(fSys, "remote", nil), nil)
+
+	for _, o := range []string{"enable_helm", "enable_exec", "enable_alpha_plugins"} {
+		assert.Equal(t, false, isCacheableBuild(fSys, "local", map[string]interface{}{o: true}), o)
+	}
+}
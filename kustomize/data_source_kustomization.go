@@ -3,17 +3,28 @@ package kustomize
 import (
 	"crypto/sha512"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"hash/crc32"
 	"log"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"sigs.k8s.io/kustomize/api/konfig"
 	"sigs.k8s.io/kustomize/api/krusty"
 	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/resource"
 	"sigs.k8s.io/kustomize/api/types"
 	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+	kyaml_utils "sigs.k8s.io/kustomize/kyaml/utils"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
 func getIDFromResources(rm resmap.ResMap) (s string, err error) {
@@ -55,6 +66,160 @@ func determinePrefix(kr *kManifestId) (p uint32) {
 	return p
 }
 
+const priorityDefaultsLegacy = "legacy"
+const priorityDefaultsHelm = "helm"
+
+// Kinds per tier, following Helm's install order. Kinds not listed,
+// e.g. custom resources, go in the empty tier, after the workloads
+// running their operators and before the webhooks.
+var helmPriorityTiers = [][]string{
+	{
+		"PriorityClass",
+		"Namespace",
+		"CustomResourceDefinition",
+	},
+	{
+		"NetworkPolicy",
+		"ResourceQuota",
+		"LimitRange",
+		"PodSecurityPolicy",
+		"PodDisruptionBudget",
+		"ServiceAccount",
+		"Secret",
+		"ConfigMap",
+		"StorageClass",
+		"PersistentVolume",
+		"PersistentVolumeClaim",
+		"ClusterRole",
+		"ClusterRoleBinding",
+		"Role",
+		"RoleBinding",
+		"IngressClass",
+		"RuntimeClass",
+	},
+	{
+		"Service",
+		"DaemonSet",
+		"Pod",
+		"ReplicationController",
+		"ReplicaSet",
+		"Deployment",
+		"HorizontalPodAutoscaler",
+		"StatefulSet",
+		"Job",
+		"CronJob",
+		"Ingress",
+	},
+	{},
+	{
+		"APIService",
+		"MutatingWebhookConfiguration",
+		"ValidatingWebhookConfiguration",
+	},
+}
+
+func getDefaultPriorityTiers(defaults string) int {
+	if defaults == priorityDefaultsHelm {
+		return len(helmPriorityTiers)
+	}
+
+	return 3
+}
+
+func getDefaultPriority(defaults string, kr *kManifestId) int {
+	if defaults == priorityDefaultsHelm {
+		other := 0
+		for i, kinds := range helmPriorityTiers {
+			if len(kinds) == 0 {
+				other = i
+			}
+			for _, k := range kinds {
+				if kr.kind == k {
+					return i
+				}
+			}
+		}
+
+		return other
+	}
+
+	switch determinePrefix(kr) {
+	case 1:
+		return 0
+	case 9:
+		return 2
+	default:
+		return 1
+	}
+}
+
+type priorityRule struct {
+	selector *types.Selector
+	tier     int
+}
+
+func getPriorityDefaultsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Default:  priorityDefaultsLegacy,
+		ValidateFunc: validation.StringInSlice([]string{
+			priorityDefaultsLegacy,
+			priorityDefaultsHelm,
+		}, false),
+	}
+}
+
+func getPriorityRulesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"target": {
+					Type:     schema.TypeList,
+					Required: true,
+					MaxItems: 1,
+					Elem:     getSelectorSchema(),
+				},
+				"tier": {
+					Type:         schema.TypeInt,
+					Required:     true,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+			},
+		},
+	}
+}
+
+func getPriorityRules(d *schema.ResourceData) (rules []priorityRule) {
+	prs, ok := d.Get("priority_rules").([]interface{})
+	if !ok {
+		return nil
+	}
+
+	for i := range prs {
+		if prs[i] == nil {
+			continue
+		}
+
+		pr := prs[i].(map[string]interface{})
+
+		t := convertMapStringInterfaceToMapStringString(
+			convertListInterfaceFirstItemToMapStringInterface(
+				pr["target"].([]interface{}),
+			),
+		)
+
+		rules = append(rules, priorityRule{
+			selector: getSelector(t),
+			tier:     pr["tier"].(int),
+		})
+	}
+
+	return rules
+}
+
 func prefixHash(p uint32, h uint32) int {
 	s := fmt.Sprintf("%01d%010d", p, h)
 	s = s[0:9]
@@ -79,13 +244,53 @@ func idSetHash(v interface{}) int {
 	return prefixHash(p, h)
 }
 
-func runKustomizeBuild(fSys filesys.FileSystem, path string, kOpts *schema.ResourceData) (rm resmap.ResMap, err error) {
+// Kustomize keeps the OpenAPI schema in package level state and every
+// Run sets it from the openapi field of each Kustomization it loads. Builds that use
+// the default schema only read that state and run in parallel, builds that
+// set their own schema need exclusive access and restore the default after.
+var openAPISchemaLock sync.RWMutex
+var openAPISchemaInit sync.Once
+
+// Parses the default schema once up front, so that
+// parallel builds never trigger lazy initialization.
+func initOpenAPISchema() {
+	openAPISchemaInit.Do(func() {
+		openAPISchemaLock.Lock()
+		openapi.Schema()
+		openAPISchemaLock.Unlock()
+	})
+}
+
+func runKustomizeBuild(fSys filesys.FileSystem, path string, kOpts map[string]interface{}) (rm resmap.ResMap, err error) {
 
 	opts := getKustomizeOptions(kOpts)
 
 	k := krusty.MakeKustomizer(opts)
 
-	rm, err = k.Run(fSys, path)
+	root := path
+	bm := getBuildMetadata(kOpts)
+	if len(bm) > 0 {
+		fSys, root, err = makeBuildMetadataFS(fSys, path, bm)
+		if err != nil {
+			return nil, fmt.Errorf("build_metadata: %s", err)
+		}
+	}
+
+	initOpenAPISchema()
+
+	if setsOpenAPISchema(fSys, root) {
+		openAPISchemaLock.Lock()
+		defer func() {
+			openapi.ResetOpenAPI()
+			openapi.Schema()
+			openAPISchemaLock.Unlock()
+		}()
+	} else {
+		openAPISchemaLock.RLock()
+		defer openAPISchemaLock.RUnlock()
+	}
+
+	rm, err = k.Run(fSys, root)
 	if err != nil {
 		return nil, fmt.Errorf("Kustomizer Run for path '%s' failed: %s", path, err)
 	}
@@ -93,8 +298,119 @@ func runKustomizeBuild(fSys filesys.FileSystem, path string, kOpts *schema.Resou
 	return rm, nil
 }
 
+// Reports whether the Kustomization in path, or any Kustomization it
+// loads as a resource or component, sets the openapi field. Nested
+// Kustomizations set the schema too, without resetting it. If a
+// Kustomization can not be read, e.g. because it is remote, assume it does.
+func setsOpenAPISchema(fSys filesys.FileSystem, path string) bool {
+	return anyKustomization(fSys, path, func(k *types.Kustomization) bool {
+		return len(k.OpenAPI) > 0
+	})
+}
+
+// Reports whether the Kustomization in path, or any Kustomization it
+// loads as a resource or component, is remote or can not be read.
+func loadsRemoteKustomizations(fSys filesys.FileSystem, path string) bool {
+	return anyKustomization(fSys, path, func(k *types.Kustomization) bool {
+		return false
+	})
+}
+
+// Walks the Kustomization in path and the Kustomizations it loads as
+// resources or components, and reports whether f is true for any of
+// them. Kustomizations that can not be read count as true.
+func anyKustomization(fSys filesys.FileSystem, path string, f func(k *types.Kustomization) bool) bool {
+	return walkKustomizations(fSys, path, f, make(map[string]bool))
+}
+
+func walkKustomizations(fSys filesys.FileSystem, path string, f func(k *types.Kustomization) bool, seen map[string]bool) bool {
+	if seen[path] {
+		return false
+	}
+	seen[path] = true
+
+	for _, n := range konfig.RecognizedKustomizationFileNames() {
+		data, err := fSys.ReadFile(filepath.Join(path, n))
+		if err != nil {
+			continue
+		}
+
+		k := types.Kustomization{}
+		err = yaml.Unmarshal(data, &k)
+		if err != nil {
+			return true
+		}
+
+		if f(&k) {
+			return true
+		}
+
+		for _, r := range append(append(k.Resources, k.Components...), k.Bases...) {
+			p := filepath.Join(path, r)
+			if fSys.Exists(p) && !fSys.IsDir(p) {
+				continue
+			}
+
+			if walkKustomizations(fSys, p, f, seen) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// Returns the arguments of an optional block
+// with at most one item, or nil if it's not set.
+func getBlockOptions(d *schema.ResourceData, key string) map[string]interface{} {
+	b, ok := d.Get(key).([]interface{})
+	if !ok || len(b) == 0 || b[0] == nil {
+		return nil
+	}
+
+	return b[0].(map[string]interface{})
+}
+
+// Sets key to a map of IDs to JSON encoded lists of problems, and
+// returns an error listing all problems, if there are any and fail is set.
+func setProblems(d *schema.ResourceData, key string, problems map[string][]string, fail bool) error {
+	m := make(map[string]string)
+	for id, msgs := range problems {
+		j, err := json.Marshal(msgs)
+		if err != nil {
+			return fmt.Errorf("%q: %s", id, err)
+		}
+		m[id] = string(j)
+	}
+	d.Set(key, m)
+
+	if len(problems) > 0 && fail {
+		return fmt.Errorf("%s", fmtProblems(problems))
+	}
+
+	return nil
+}
+
 func setGeneratedAttributes(d *schema.ResourceData, rm resmap.ResMap) error {
-	ids, idsPrio, err := flattenKustomizationIDs(rm)
+	err := setValidationErrors(d, rm)
+	if err != nil {
+		return err
+	}
+
+	err = setAdmissionPolicyViolations(d, rm)
+	if err != nil {
+		return err
+	}
+
+	err = setAPIDeprecations(d, rm)
+	if err != nil {
+		return err
+	}
+
+	defaults, _ := d.Get("priority_defaults").(string)
+	ids, idsPrio, err := flattenKustomizationIDs(rm, defaults, getPriorityRules(d))
 	if err != nil {
 		return fmt.Errorf("couldn't flatten kustomization IDs: %s", err)
 	}
@@ -107,6 +423,28 @@ func setGeneratedAttributes(d *schema.ResourceData, rm resmap.ResMap) error {
 	}
 	d.Set("manifests", resources)
 
+	d.Set("namespaces", flattenKustomizationNamespaces(rm))
+
+	kinds, err := flattenKustomizationKinds(rm)
+	if err != nil {
+		return fmt.Errorf("couldn't flatten kinds: %s", err)
+	}
+	d.Set("kinds", kinds)
+
+	images, err := flattenKustomizationImages(rm)
+	if err != nil {
+		return fmt.Errorf("couldn't flatten container images: %s", err)
+	}
+	d.Set("container_images", images)
+
+	graph, err := flattenKustomizationGraph(rm)
+	if err != nil {
+		return fmt.Errorf("couldn't flatten ID graph: %s", err)
+	}
+	d.Set("ids_graph", graph)
+
+	d.Set("cluster_scoped_ids", flattenKustomizationClusterScopedIDs(rm))
+
 	id, err := getIDFromResources(rm)
 	if err != nil {
 		return fmt.Errorf("couldn't get ID from resources: %s", err)
@@ -116,20 +454,287 @@ func setGeneratedAttributes(d *schema.ResourceData, rm resmap.ResMap) error {
 	return nil
 }
 
-func getKustomizeOptions(d *schema.ResourceData) (opts *krusty.Options) {
+// Adds the computed attributes set by setGeneratedAttributes
+// to the schema of a data source.
+func withGeneratedAttributesSchema(s map[string]*schema.Schema) map[string]*schema.Schema {
+	for k, v := range map[string]*schema.Schema{
+		"ids": &schema.Schema{
+			Type:     schema.TypeSet,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+			Set:      idSetHash,
+		},
+		"ids_prio": &schema.Schema{
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem: &schema.Schema{
+				Type: schema.TypeSet,
+				Set:  idSetHash,
+			},
+		},
+		"manifests": &schema.Schema{
+			Type:     schema.TypeMap,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"namespaces": &schema.Schema{
+			Type:     schema.TypeSet,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"kinds": &schema.Schema{
+			Type:     schema.TypeMap,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"container_images": &schema.Schema{
+			Type:     schema.TypeSet,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"ids_graph": &schema.Schema{
+			Type:     schema.TypeMap,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"validation_errors": &schema.Schema{
+			Type:     schema.TypeMap,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"validation_skipped_ids": &schema.Schema{
+			Type:     schema.TypeSet,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+			Set:      idSetHash,
+		},
+		"admission_policy_violations": &schema.Schema{
+			Type:     schema.TypeMap,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"api_deprecations": &schema.Schema{
+			Type:     schema.TypeMap,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"cluster_scoped_ids": &schema.Schema{
+			Type:     schema.TypeSet,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+			Set:      idSetHash,
+		},
+	} {
+		s[k] = v
+	}
 
-	opts = krusty.MakeDefaultOptions()
+	return s
+}
+
+func getKustomizeOptionsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"load_restrictor": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"enable_alpha_plugins": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"enable_exec": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"enable_helm": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"helm_path": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"helm_api_versions": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"helm_kube_version": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"helm_debug": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"add_managedby_label": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"build_metadata": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: validation.StringInSlice(types.BuildMetadataOptions, false),
+					},
+				},
+				"reorder": {
+					Type:     schema.TypeString,
+					Optional: true,
+					ValidateFunc: validation.StringInSlice([]string{
+						string(krusty.ReorderOptionLegacy),
+						string(krusty.ReorderOptionNone),
+					}, false),
+				},
+			},
+		},
+	}
+}
 
+// Enables origin annotations if track_origins is set. Returns true if
+// they have to be stripped again after the build, because they were
+// not requested in kustomize_options.
+func enableOriginAnnotations(d *schema.ResourceData, kOpts map[string]interface{}) (strip bool) {
+	if !d.Get("track_origins").(bool) {
+		return false
+	}
+
+	bm := getBuildMetadata(kOpts)
+	for _, v := range bm {
+		if v == types.OriginAnnotations {
+			return false
+		}
+	}
+
+	l := []interface{}{types.OriginAnnotations}
+	for _, v := range bm {
+		l = append(l, v)
+	}
+	kOpts["build_metadata"] = l
+
+	return true
+}
+
+func setOrigins(d *schema.ResourceData, rm resmap.ResMap, strip bool) error {
+	if !d.Get("track_origins").(bool) {
+		return nil
+	}
+
+	origins, err := flattenKustomizationOrigins(rm)
+	if err != nil {
+		return fmt.Errorf("couldn't flatten origins: %s", err)
+	}
+	d.Set("origins", origins)
+
+	if strip {
+		err = stripOriginAnnotations(rm)
+		if err != nil {
+			return fmt.Errorf("couldn't strip origin annotations: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func getKustomizeOptionsBlock(d *schema.ResourceData) map[string]interface{} {
 	kOptsList := d.Get("kustomize_options").([]interface{})
 
-	if len(kOptsList) == 0 {
+	if len(kOptsList) == 0 || kOptsList[0] == nil {
+		return nil
+	}
+
+	return kOptsList[0].(map[string]interface{})
+}
+
+// Merges the data source's kustomize_options over the provider level
+// defaults. Only arguments set in the data source's configuration
+// take precedence over the provider's.
+func getMergedKustomizeOptions(d *schema.ResourceData, defaults map[string]interface{}) map[string]interface{} {
+	kOpts := make(map[string]interface{})
+	for k, v := range defaults {
+		kOpts[k] = v
+	}
+
+	dsOpts := getKustomizeOptionsBlock(d)
+	configured := getConfiguredKustomizeOptions(d)
+	for k, v := range dsOpts {
+		if configured != nil && !configured[k] {
+			continue
+		}
+
+		// without the raw config, fall back to zero values being unset
+		if configured == nil && isZeroKustomizeOption(v) {
+			continue
+		}
+
+		kOpts[k] = v
+	}
+
+	return kOpts
+}
+
+func getConfiguredKustomizeOptions(d *schema.ResourceData) map[string]bool {
+	raw := d.GetRawConfig()
+	if raw.IsNull() || !raw.IsKnown() || !raw.Type().IsObjectType() || !raw.Type().HasAttribute("kustomize_options") {
+		return nil
+	}
+
+	configured := make(map[string]bool)
+
+	l := raw.GetAttr("kustomize_options")
+	if l.IsNull() || !l.IsKnown() || l.LengthInt() == 0 {
+		return configured
+	}
+
+	block := l.AsValueSlice()[0]
+	if block.IsNull() || !block.IsKnown() {
+		return configured
+	}
+
+	for k, v := range block.AsValueMap() {
+		if !v.IsNull() {
+			configured[k] = true
+		}
+	}
+
+	return configured
+}
+
+func isZeroKustomizeOption(v interface{}) bool {
+	switch t := v.(type) {
+	case bool:
+		return !t
+	case string:
+		return t == ""
+	case []interface{}:
+		return len(t) == 0
+	}
+
+	return v == nil
+}
+
+func getKustomizeOptions(kOpts map[string]interface{}) (opts *krusty.Options) {
+
+	opts = krusty.MakeDefaultOptions()
+
+	if len(kOpts) == 0 {
 		return opts
 	}
 
-	kOpts := kOptsList[0].(map[string]interface{})
 	getBoolOpt := func(key string) bool {
 		return kOpts[key] != nil && kOpts[key].(bool)
 	}
+	getStringOpt := func(key string) string {
+		if kOpts[key] == nil {
+			return ""
+		}
+		return kOpts[key].(string)
+	}
 
 	enableHelm := getBoolOpt("enable_helm")
 	enableExec := getBoolOpt("enable_exec")
@@ -141,18 +746,367 @@ func getKustomizeOptions(d *schema.ResourceData) (opts *krusty.Options) {
 		opts.PluginConfig = types.EnabledPluginConfig(types.BploUseStaticallyLinked)
 	}
 
-	if kOpts["load_restrictor"] != nil {
-		if kOpts["load_restrictor"].(string) == "none" {
-			opts.LoadRestrictions = types.LoadRestrictionsNone
-		}
+	if getStringOpt("load_restrictor") == "none" {
+		opts.LoadRestrictions = types.LoadRestrictionsNone
+	}
+
+	if r := getStringOpt("reorder"); r != "" {
+		opts.Reorder = krusty.ReorderOption(r)
 	}
 
+	opts.AddManagedbyLabel = getBoolOpt("add_managedby_label")
+
 	opts.PluginConfig.FnpLoadingOptions.EnableExec = enableExec
 	opts.PluginConfig.HelmConfig.Enabled = enableHelm
 
-	if enableHelm && kOpts["helm_path"] != nil {
-		opts.PluginConfig.HelmConfig.Command = kOpts["helm_path"].(string)
+	if enableHelm {
+		if p := getStringOpt("helm_path"); p != "" {
+			opts.PluginConfig.HelmConfig.Command = p
+		}
+
+		if kOpts["helm_api_versions"] != nil {
+			opts.PluginConfig.HelmConfig.ApiVersions = convertListInterfaceToListString(
+				kOpts["helm_api_versions"].([]interface{}),
+			)
+		}
+
+		opts.PluginConfig.HelmConfig.KubeVersion = getStringOpt("helm_kube_version")
+		opts.PluginConfig.HelmConfig.Debug = getBoolOpt("helm_debug")
 	}
 
 	return opts
 }
+
+func getBuildMetadata(kOpts map[string]interface{}) []string {
+	if kOpts["build_metadata"] == nil {
+		return nil
+	}
+
+	return convertListInterfaceToListString(kOpts["build_metadata"].([]interface{}))
+}
+
+var _ filesys.FileSystem = buildMetadataFileSystem{}
+
+// Build metadata is not part of krusty.Options but read from the
+// Kustomization. This filesys.FileSystem adds it to the root
+// Kustomization when kustomize reads it.
+type buildMetadataFileSystem struct {
+	filesys.FileSystem
+	root          string
+	buildMetadata []string
+}
+
+// Remote roots can't be rewritten, so they are loaded as the only
+// resource of a virtual Kustomization that sets the build metadata.
+// Returns the file system and the path to build.
+func makeBuildMetadataFS(fSys filesys.FileSystem, path string, bm []string) (filesys.FileSystem, string, error) {
+	if !fSys.IsDir(path) {
+		k := types.Kustomization{
+			Resources:     []string{path},
+			BuildMetadata: bm,
+		}
+
+		data, err := yaml.Marshal(k)
+		if err != nil {
+			return nil, "", err
+		}
+
+		root := filepath.Join(os.TempDir(), "terraform-provider-kustomization-build-metadata")
+		lfs, err := makeLayeredFS(fSys, root, map[string]string{
+			konfig.DefaultKustomizationFileName(): string(data),
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		return lfs, root, nil
+	}
+
+	root, _, err := fSys.CleanedAbs(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return buildMetadataFileSystem{
+		FileSystem:    fSys,
+		root:          root.String(),
+		buildMetadata: bm,
+	}, path, nil
+}
+
+func (bfs buildMetadataFileSystem) isRootKustomization(name string) bool {
+	if filepath.Dir(name) != bfs.root {
+		return false
+	}
+
+	for _, kfn := range konfig.RecognizedKustomizationFileNames() {
+		if filepath.Base(name) == kfn {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (bfs buildMetadataFileSystem) ReadFile(name string) ([]byte, error) {
+	data, err := bfs.FileSystem.ReadFile(name)
+	if err != nil || !bfs.isRootKustomization(name) {
+		return data, err
+	}
+
+	rn, perr := yaml.Parse(string(data))
+	if perr != nil {
+		// let kustomize report invalid Kustomizations
+		return data, err
+	}
+
+	seq, perr := rn.Pipe(yaml.LookupCreate(yaml.SequenceNode, "buildMetadata"))
+	if perr != nil {
+		return data, err
+	}
+
+	existing := make(map[string]bool)
+	for _, n := range seq.Content() {
+		existing[n.Value] = true
+	}
+
+	for _, bm := range bfs.buildMetadata {
+		if existing[bm] {
+			continue
+		}
+		seq.YNode().Content = append(seq.YNode().Content, yaml.NewStringRNode(bm).YNode())
+	}
+
+	s, perr := rn.String()
+	if perr != nil {
+		return data, err
+	}
+
+	return []byte(s), nil
+}
+
+func getSelectorSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"group": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"version": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"kind": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"label_selector": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"annotation_selector": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func getSelector(t map[string]string) *types.Selector {
+	s := &types.Selector{}
+	s.Group = t["group"]
+	s.Version = t["version"]
+	s.Kind = t["kind"]
+	s.Name = t["name"]
+	s.Namespace = t["namespace"]
+	s.AnnotationSelector = t["annotation_selector"]
+	s.LabelSelector = t["label_selector"]
+
+	return s
+}
+
+func getSetSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"target": {
+					Type:     schema.TypeList,
+					Required: true,
+					MaxItems: 1,
+					Elem:     getSelectorSchema(),
+				},
+				"field_path": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"value": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"create": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+			},
+		},
+	}
+}
+
+func getFilterSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem:     getSelectorSchema(),
+	}
+}
+
+// Keeps resources matching any include block, or all resources if there
+// are none, and removes resources matching any exclude block.
+func applyFilters(d *schema.ResourceData, rm resmap.ResMap) error {
+	include, err := selectResources(d, rm, "include")
+	if err != nil {
+		return err
+	}
+
+	exclude, err := selectResources(d, rm, "exclude")
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rm.Resources() {
+		id := r.CurId()
+		if (include != nil && !include[id]) || exclude[id] {
+			err := rm.Remove(id)
+			if err != nil {
+				return fmt.Errorf("removing %q: %s", getResourceID(r).string(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func selectResources(d *schema.ResourceData, rm resmap.ResMap, key string) (selected map[resid.ResId]bool, err error) {
+	ss, ok := d.Get(key).([]interface{})
+	if !ok || len(ss) == 0 {
+		return nil, nil
+	}
+
+	selected = make(map[resid.ResId]bool)
+	for i := range ss {
+		t, _ := ss[i].(map[string]interface{})
+
+		rs, err := rm.Select(*getSelector(convertMapStringInterfaceToMapStringString(t)))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%d: invalid selector: %s", key, i, err)
+		}
+
+		for _, r := range rs {
+			selected[r.CurId()] = true
+		}
+	}
+
+	return selected, nil
+}
+
+func applySetValues(d *schema.ResourceData, rm resmap.ResMap) error {
+	ss := d.Get("set").([]interface{})
+	for i := range ss {
+		if ss[i] == nil {
+			continue
+		}
+
+		s := ss[i].(map[string]interface{})
+		fieldPath := s["field_path"].(string)
+		value := s["value"].(string)
+		create := s["create"].(bool)
+
+		t := convertMapStringInterfaceToMapStringString(
+			convertListInterfaceFirstItemToMapStringInterface(
+				s["target"].([]interface{}),
+			),
+		)
+
+		rs, err := rm.Select(*getSelector(t))
+		if err != nil {
+			return fmt.Errorf("set %q: invalid target: %s", fieldPath, err)
+		}
+
+		if len(rs) == 0 {
+			return fmt.Errorf("set %q: target did not match any resources", fieldPath)
+		}
+
+		for _, r := range rs {
+			err := setResourceField(r, fieldPath, value, create)
+			if err != nil {
+				kr := &kManifestId{
+					group:     r.CurId().Group,
+					kind:      r.CurId().Kind,
+					namespace: r.GetNamespace(),
+					name:      r.GetName(),
+				}
+				return fmt.Errorf("set %q on %q: %s", fieldPath, kr.string(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func setResourceField(r *resource.Resource, fieldPath string, value string, create bool) error {
+	createKind := yaml.Kind(0)
+	if create {
+		createKind = yaml.ScalarNode
+	}
+
+	path := kyaml_utils.SmarterPathSplitter(fieldPath, ".")
+
+	// label and annotation values have to be strings
+	// even if the value looks like a number or bool
+	isStringMap := false
+	if l := len(path); l >= 3 && path[l-3] == "metadata" {
+		isStringMap = path[l-2] == "labels" || path[l-2] == "annotations"
+	}
+
+	fl, err := r.Pipe(&yaml.PathMatcher{
+		Path:   path,
+		Create: createKind,
+	})
+	if err != nil {
+		return err
+	}
+
+	fields, err := fl.Elements()
+	if err != nil {
+		return err
+	}
+
+	if len(fields) == 0 {
+		return fmt.Errorf("field does not exist, set create = true to add it")
+	}
+
+	for _, f := range fields {
+		if f.YNode().Kind != yaml.ScalarNode {
+			return fmt.Errorf("field is not a scalar value")
+		}
+
+		// only replace the value, an existing field keeps its type
+		f.YNode().Value = value
+
+		if isStringMap {
+			f.YNode().Tag = yaml.NodeTagString
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package kustomize
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+func dataSourceKustomizationAPIResources() *schema.Resource {
+	return &schema.Resource{
+		Read: kustomizationAPIResources,
+
+		Schema: map[string]*schema.Schema{
+			"server_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"group_versions": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"preferred_versions": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"kinds": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"cluster_scoped_kinds": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func kustomizationAPIResources(d *schema.ResourceData, m interface{}) error {
+	dc := m.(*Config).Discovery
+
+	sv, err := dc.ServerVersion()
+	if err != nil {
+		return fmt.Errorf("kustomizationAPIResources: %s", err)
+	}
+
+	groups, lists, err := dc.ServerGroupsAndResources()
+	if err != nil {
+		// unavailable aggregated APIs, e.g. a broken metrics-server,
+		// must not prevent using the groups that were discovered
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return fmt.Errorf("kustomizationAPIResources: %s", err)
+		}
+	}
+
+	groupVersions, preferredVersions := flattenAPIGroups(groups)
+	kinds, clusterScopedKinds, err := flattenAPIResourceLists(lists)
+	if err != nil {
+		return fmt.Errorf("kustomizationAPIResources: %s", err)
+	}
+
+	d.Set("server_version", sv.GitVersion)
+	d.Set("group_versions", groupVersions)
+	d.Set("preferred_versions", preferredVersions)
+	d.Set("kinds", kinds)
+	d.Set("cluster_scoped_kinds", clusterScopedKinds)
+
+	h := sha512.New()
+	fmt.Fprintf(h, "%s\n", sv.GitVersion)
+	for _, gv := range groupVersions {
+		fmt.Fprintf(h, "%s\n", gv)
+	}
+	for _, k := range kinds {
+		fmt.Fprintf(h, "%s\n", k)
+	}
+	d.SetId(hex.EncodeToString(h.Sum(nil)))
+
+	return nil
+}
+
+// Kinds and preferred versions use _ for the core
+// group, like the group in resource IDs.
+func apiGroupName(group string) string {
+	if group == "" {
+		return "_"
+	}
+
+	return group
+}
+
+func flattenAPIGroups(groups []*k8smetav1.APIGroup) (groupVersions []string, preferredVersions map[string]string) {
+	preferredVersions = make(map[string]string)
+	for _, g := range groups {
+		for _, v := range g.Versions {
+			groupVersions = append(groupVersions, v.GroupVersion)
+		}
+
+		preferredVersions[apiGroupName(g.Name)] = g.PreferredVersion.Version
+	}
+
+	sort.Strings(groupVersions)
+
+	return groupVersions, preferredVersions
+}
+
+func flattenAPIResourceLists(lists []*k8smetav1.APIResourceList) (kinds []string, clusterScopedKinds []string, err error) {
+	seen := make(map[string]bool)
+	for _, l := range lists {
+		gv, err := k8sschema.ParseGroupVersion(l.GroupVersion)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, r := range l.APIResources {
+			// skip subresources, e.g. deployments/scale
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+
+			k := fmt.Sprintf("%s/%s", apiGroupName(gv.Group), r.Kind)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+
+			kinds = append(kinds, k)
+			if !r.Namespaced {
+				clusterScopedKinds = append(clusterScopedKinds, k)
+			}
+		}
+	}
+
+	sort.Strings(kinds)
+	sort.Strings(clusterScopedKinds)
+
+	return kinds, clusterScopedKinds, nil
+}
@@ -0,0 +1,109 @@
+package kustomize
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestAccDataSourceKustomizationAPIResources_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		//PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceKustomizationAPIResourcesConfig_basic(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestMatchResourceAttr("data.kustomization_api_resources.test", "server_version", regexp.MustCompile(`^v1\.`)),
+					resource.TestCheckTypeSetElemAttr("data.kustomization_api_resources.test", "group_versions.*", "apps/v1"),
+					resource.TestCheckResourceAttr("data.kustomization_api_resources.test", "preferred_versions._", "v1"),
+					resource.TestCheckTypeSetElemAttr("data.kustomization_api_resources.test", "kinds.*", "apps/Deployment"),
+					resource.TestCheckTypeSetElemAttr("data.kustomization_api_resources.test", "cluster_scoped_kinds.*", "_/Namespace"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceKustomizationAPIResourcesConfig_basic() string {
+	return `
+data "kustomization_api_resources" "test" {}
+`
+}
+
+func TestKustomizationAPIResources(t *testing.T) {
+	dc := &fakediscovery.FakeDiscovery{
+		Fake: &k8stesting.Fake{},
+		FakedServerVersion: &version.Info{
+			GitVersion: "v1.29.2",
+		},
+	}
+	dc.Resources = []*k8smetav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []k8smetav1.APIResource{
+				{Name: "namespaces", Kind: "Namespace", Namespaced: false},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []k8smetav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true},
+				{Name: "deployments/scale", Kind: "Scale", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "cert-manager.io/v1",
+			APIResources: []k8smetav1.APIResource{
+				{Name: "certificates", Kind: "Certificate", Namespaced: true},
+				{Name: "clusterissuers", Kind: "ClusterIssuer", Namespaced: false},
+			},
+		},
+		{
+			GroupVersion: "cert-manager.io/v1alpha2",
+			APIResources: []k8smetav1.APIResource{
+				{Name: "certificates", Kind: "Certificate", Namespaced: true},
+			},
+		},
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceKustomizationAPIResources().Schema, map[string]interface{}{})
+
+	err := kustomizationAPIResources(d, &Config{Discovery: dc})
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, "v1.29.2", d.Get("server_version").(string), nil)
+	assert.ElementsMatch(t, []interface{}{
+		"v1",
+		"apps/v1",
+		"cert-manager.io/v1",
+		"cert-manager.io/v1alpha2",
+	}, d.Get("group_versions").(*schema.Set).List(), nil)
+	assert.Equal(t, map[string]interface{}{
+		"_":               "v1",
+		"apps":            "v1",
+		"cert-manager.io": "v1",
+	}, d.Get("preferred_versions").(map[string]interface{}), nil)
+	assert.ElementsMatch(t, []interface{}{
+		"_/ConfigMap",
+		"_/Namespace",
+		"apps/Deployment",
+		"cert-manager.io/Certificate",
+		"cert-manager.io/ClusterIssuer",
+	}, d.Get("kinds").(*schema.Set).List(), nil)
+	assert.ElementsMatch(t, []interface{}{
+		"_/Namespace",
+		"cert-manager.io/ClusterIssuer",
+	}, d.Get("cluster_scoped_kinds").(*schema.Set).List(), nil)
+	assert.NotEqual(t, "", d.Id(), nil)
+}
@@ -2,6 +2,8 @@ package kustomize
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 
@@ -10,62 +12,45 @@ import (
 
 func dataSourceKustomization() *schema.Resource {
 	return &schema.Resource{
-		Read: kustomizationBuild,
+		ReadContext: readWithAPIDeprecationWarnings(kustomizationBuild),
 
-		Schema: map[string]*schema.Schema{
+		Schema: withGeneratedAttributesSchema(map[string]*schema.Schema{
 			"path": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
+				Type:         schema.TypeString,
+				Optional:     true,
+				AtLeastOneOf: []string{"path", "files"},
 			},
-			"kustomize_options": &schema.Schema{
-				Type:     schema.TypeList,
-				Optional: true,
-				MaxItems: 1,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"load_restrictor": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-						"enable_alpha_plugins": {
-							Type:     schema.TypeBool,
-							Optional: true,
-						},
-						"enable_exec": {
-							Type:     schema.TypeBool,
-							Optional: true,
-						},
-						"enable_helm": {
-							Type:     schema.TypeBool,
-							Optional: true,
-						},
-						"helm_path": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-					},
-				},
+			"files": &schema.Schema{
+				Type:         schema.TypeMap,
+				Optional:     true,
+				Elem:         &schema.Schema{Type: schema.TypeString},
+				AtLeastOneOf: []string{"path", "files"},
 			},
-			"ids": &schema.Schema{
-				Type:     schema.TypeSet,
-				Computed: true,
-				Elem:     &schema.Schema{Type: schema.TypeString},
-				Set:      idSetHash,
+			"kustomize_options":   getKustomizeOptionsSchema(),
+			"set":                 getSetSchema(),
+			"include":             getFilterSchema(),
+			"exclude":             getFilterSchema(),
+			"priority_defaults":   getPriorityDefaultsSchema(),
+			"priority_rules":      getPriorityRulesSchema(),
+			"guardrails":          getGuardrailsSchema(),
+			"policy":              getPolicySchema(),
+			"schema_validation":   getSchemaValidationSchema(),
+			"admission_policies":  getAdmissionPoliciesSchema(),
+			"target_kube_version": getTargetKubeVersionSchema(),
+			"cache_key": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
 			},
-			"ids_prio": &schema.Schema{
-				Type:     schema.TypeList,
-				Computed: true,
-				Elem: &schema.Schema{
-					Type: schema.TypeSet,
-					Set:  idSetHash,
-				},
+			"track_origins": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
 			},
-			"manifests": &schema.Schema{
+			"origins": &schema.Schema{
 				Type:     schema.TypeMap,
 				Computed: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
-		},
+		}),
 	}
 }
 
@@ -74,15 +59,73 @@ func kustomizationBuild(d *schema.ResourceData, m interface{}) error {
 
 	fSys := filesys.MakeFsOnDisk()
 
-	// mutex as tmp workaround for upstream bug
-	// https://github.com/kubernetes-sigs/kustomize/issues/3659
-	mu := m.(*Config).Mutex
-	mu.Lock()
-	rm, err := runKustomizeBuild(fSys, path, d)
-	mu.Unlock()
+	files := d.Get("files").(map[string]interface{})
+	if len(files) > 0 {
+		var err error
+		fSys, err = makeInMemoryFS(convertMapStringInterfaceToMapStringString(files))
+		if err != nil {
+			return fmt.Errorf("kustomizationBuild: %s", err)
+		}
+
+		// default to the root of the virtual tree
+		if path == "" {
+			path = "."
+		}
+	}
+
+	kOpts := getMergedKustomizeOptions(d, m.(*Config).KustomizeOptions)
+	stripOrigins := enableOriginAnnotations(d, kOpts)
+
+	// fmt prints maps sorted by key
+	rm, err := runCachedKustomizeBuild(m.(*Config).BuildCache, fSys, path, kOpts, d.Get("cache_key").(string), fmt.Sprint(files))
+	if err != nil {
+		return fmt.Errorf("kustomizationBuild: %s", err)
+	}
+
+	err = applySetValues(d, rm)
+	if err != nil {
+		return fmt.Errorf("kustomizationBuild: %s", err)
+	}
+
+	err = applyFilters(d, rm)
+	if err != nil {
+		return fmt.Errorf("kustomizationBuild: %s", err)
+	}
+
+	err = enforceGuardrails(d, rm, m.(*Config).Guardrails)
+	if err != nil {
+		return fmt.Errorf("kustomizationBuild: %s", err)
+	}
+
+	err = enforcePolicies(d, rm)
+	if err != nil {
+		return fmt.Errorf("kustomizationBuild: %s", err)
+	}
+
+	err = setOrigins(d, rm, stripOrigins)
 	if err != nil {
 		return fmt.Errorf("kustomizationBuild: %s", err)
 	}
 
 	return setGeneratedAttributes(d, rm)
 }
+
+// Builds a file system from the files map, the kustomization
+// can not load any files outside of this virtual tree.
+func makeInMemoryFS(files map[string]string) (fSys filesys.FileSystem, err error) {
+	fSys = filesys.MakeFsInMemory()
+
+	for name, content := range files {
+		p := filepath.Clean(name)
+		if filepath.IsAbs(p) || p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("invalid file name %q, must be a relative path inside the virtual tree", name)
+		}
+
+		err = fSys.WriteFile(filepath.Join(filesys.Separator, p), []byte(content))
+		if err != nil {
+			return nil, fmt.Errorf("writing file %q failed: %s", name, err)
+		}
+	}
+
+	return fSys, nil
+}
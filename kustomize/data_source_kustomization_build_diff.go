@@ -0,0 +1,322 @@
+package kustomize
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// kustomize appends the first 10 characters of a hex encoded hash to the
+// names of generated ConfigMaps and Secrets, replacing 0, 1, 3, a and e.
+var hashSuffixRegexp = regexp.MustCompile(`^(.+)-[2456789bcdfghkmt]{10}$`)
+
+func dataSourceKustomizationBuildDiff() *schema.Resource {
+	return &schema.Resource{
+		Read: kustomizationBuildDiff,
+
+		Schema: map[string]*schema.Schema{
+			"old_manifests": &schema.Schema{
+				Type:     schema.TypeMap,
+				Required: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"new_manifests": &schema.Schema{
+				Type:     schema.TypeMap,
+				Required: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"ignore_hash_suffixes": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"added_ids": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      idSetHash,
+			},
+			"removed_ids": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      idSetHash,
+			},
+			"changed_ids": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      idSetHash,
+			},
+			"diffs": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+// A side of the diff, objects by ID and the
+// IDs as they were given, by normalized ID.
+type buildDiffSide struct {
+	objs map[string]map[string]interface{}
+	ids  map[string]string
+}
+
+// Compares two sets of manifests offline, e.g. the builds of
+// two kustomizations or of the same kustomization at two refs.
+func kustomizationBuildDiff(d *schema.ResourceData, m interface{}) error {
+	oldManifests := d.Get("old_manifests").(map[string]interface{})
+	newManifests := d.Get("new_manifests").(map[string]interface{})
+	ignoreHashSuffixes := d.Get("ignore_hash_suffixes").(bool)
+
+	o, err := getBuildDiffSide(oldManifests, ignoreHashSuffixes)
+	if err != nil {
+		return fmt.Errorf("kustomizationBuildDiff: old_manifests: %s", err)
+	}
+
+	n, err := getBuildDiffSide(newManifests, ignoreHashSuffixes)
+	if err != nil {
+		return fmt.Errorf("kustomizationBuildDiff: new_manifests: %s", err)
+	}
+
+	added := []string{}
+	removed := []string{}
+	changed := []string{}
+	diffs := make(map[string]string)
+
+	for nid, id := range n.ids {
+		if _, ok := o.ids[nid]; !ok {
+			added = append(added, id)
+		}
+	}
+
+	for nid, id := range o.ids {
+		if _, ok := n.ids[nid]; !ok {
+			removed = append(removed, id)
+			continue
+		}
+
+		if k := mustParseProviderId(nid); k.group == "" && k.kind == "Secret" {
+			maskSecretValues(o.objs[nid], n.objs[nid])
+		}
+
+		changes := getFieldChanges("", o.objs[nid], n.objs[nid])
+		if len(changes) > 0 {
+			changed = append(changed, n.ids[nid])
+			diffs[n.ids[nid]] = strings.Join(changes, "\n") + "\n"
+		}
+	}
+
+	d.Set("added_ids", added)
+	d.Set("removed_ids", removed)
+	d.Set("changed_ids", changed)
+	d.Set("diffs", diffs)
+	d.SetId(getManifestsID(map[string]interface{}{
+		"old_manifests": getManifestsID(oldManifests),
+		"new_manifests": getManifestsID(newManifests),
+	}))
+
+	return nil
+}
+
+func getBuildDiffSide(manifests map[string]interface{}, ignoreHashSuffixes bool) (*buildDiffSide, error) {
+	s := &buildDiffSide{
+		objs: make(map[string]map[string]interface{}),
+		ids:  make(map[string]string),
+	}
+
+	for id, v := range manifests {
+		_, err := parseProviderId(id)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := manifestToJSON([]byte(v.(string)))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", id, err)
+		}
+
+		var obj map[string]interface{}
+		err = json.Unmarshal(body, &obj)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", id, err)
+		}
+
+		s.objs[id] = obj
+		s.ids[id] = id
+	}
+
+	if ignoreHashSuffixes {
+		err := s.stripHashSuffixes()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Removes the hash suffixes from the names of generated ConfigMaps
+// and Secrets, from their IDs and from all references to them. Objects
+// that only differ in their hash suffixes can't be told apart.
+func (s *buildDiffSide) stripHashSuffixes() error {
+	names := make(map[string]string)
+	for id := range s.objs {
+		k := mustParseProviderId(id)
+		if k.group != "" || (k.kind != "ConfigMap" && k.kind != "Secret") {
+			continue
+		}
+
+		match := hashSuffixRegexp.FindStringSubmatch(k.name)
+		if match == nil {
+			continue
+		}
+		names[k.name] = match[1]
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	sorted := make([]string, 0, len(s.objs))
+	for id := range s.objs {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	objs := make(map[string]map[string]interface{})
+	ids := make(map[string]string)
+	for _, id := range sorted {
+		k := mustParseProviderId(id)
+		if base, ok := names[k.name]; ok && k.group == "" && (k.kind == "ConfigMap" || k.kind == "Secret") {
+			k.name = base
+		}
+
+		if other, ok := ids[k.string()]; ok {
+			return fmt.Errorf("%q and %q have the same ID %q without hash suffixes", other, s.ids[id], k.string())
+		}
+
+		objs[k.string()] = replaceStrings(s.objs[id], names).(map[string]interface{})
+		ids[k.string()] = s.ids[id]
+	}
+
+	s.objs = objs
+	s.ids = ids
+
+	return nil
+}
+
+// Replaces string values that equal a key of names, recursively.
+func replaceStrings(v interface{}, names map[string]string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		r := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			r[k] = replaceStrings(e, names)
+		}
+		return r
+	case []interface{}:
+		r := make([]interface{}, len(v))
+		for i, e := range v {
+			r[i] = replaceStrings(e, names)
+		}
+		return r
+	case string:
+		if n, ok := names[v]; ok {
+			return n
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Lists the fields that differ between two values, one line per field.
+// Added fields start with +, removed fields with - and changed fields
+// with ~, followed by the path and the values in YAML flow style.
+func getFieldChanges(path string, o interface{}, n interface{}) (changes []string) {
+	switch ov := o.(type) {
+	case map[string]interface{}:
+		nv, ok := n.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := make(map[string]bool)
+		for k := range ov {
+			keys[k] = true
+		}
+		for k := range nv {
+			keys[k] = true
+		}
+
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			p := k
+			if path != "" {
+				p = fmt.Sprintf("%s.%s", path, k)
+			}
+
+			oe, inOld := ov[k]
+			ne, inNew := nv[k]
+			switch {
+			case !inOld:
+				changes = append(changes, fmt.Sprintf("+ %s: %s", p, fmtFieldValue(ne)))
+			case !inNew:
+				changes = append(changes, fmt.Sprintf("- %s: %s", p, fmtFieldValue(oe)))
+			default:
+				changes = append(changes, getFieldChanges(p, oe, ne)...)
+			}
+		}
+
+		return changes
+	case []interface{}:
+		nv, ok := n.([]interface{})
+		if !ok {
+			break
+		}
+
+		for i := 0; i < len(ov) || i < len(nv); i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(ov):
+				changes = append(changes, fmt.Sprintf("+ %s: %s", p, fmtFieldValue(nv[i])))
+			case i >= len(nv):
+				changes = append(changes, fmt.Sprintf("- %s: %s", p, fmtFieldValue(ov[i])))
+			default:
+				changes = append(changes, getFieldChanges(p, ov[i], nv[i])...)
+			}
+		}
+
+		return changes
+	}
+
+	if reflect.DeepEqual(o, n) {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("~ %s: %s -> %s", path, fmtFieldValue(o), fmtFieldValue(n))}
+}
+
+// JSON is valid YAML flow style, and
+// quotes strings that look like numbers.
+func fmtFieldValue(v interface{}) string {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return string(j)
+}
@@ -0,0 +1,168 @@
+package kustomize
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccDataSourceKustomizationBuildDiff_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		//PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceKustomizationBuildDiffConfig_basic(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_build_diff.test", "added_ids.#", "1"),
+					resource.TestCheckTypeSetElemAttr("data.kustomization_build_diff.test", "added_ids.*", "apps/Deployment/test-basic/test2"),
+					resource.TestCheckResourceAttr("data.kustomization_build_diff.test", "removed_ids.#", "0"),
+					resource.TestCheckResourceAttr("data.kustomization_build_diff.test", "changed_ids.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceKustomizationBuildDiffConfig_basic() string {
+	return `
+data "kustomization_build" "initial" {
+	path = "test_kustomizations/basic/initial"
+}
+
+data "kustomization_build" "modified" {
+	path = "test_kustomizations/basic/modified"
+}
+
+data "kustomization_build_diff" "test" {
+	old_manifests = data.kustomization_build.initial.manifests
+	new_manifests = data.kustomization_build.modified.manifests
+}
+`
+}
+
+const buildDiffTestDeployment = `{
+	"apiVersion": "apps/v1",
+	"kind": "Deployment",
+	"metadata": {"name": "test", "namespace": "test"},
+	"spec": {
+		"replicas": 1,
+		"template": {"spec": {"volumes": [{"name": "config", "configMap": {"name": "%s"}}]}}
+	}
+}`
+
+func getBuildDiffTestData(t *testing.T, oldManifests map[string]interface{}, newManifests map[string]interface{}, ignoreHashSuffixes bool) *schema.ResourceData {
+	return schema.TestResourceDataRaw(t, dataSourceKustomizationBuildDiff().Schema, map[string]interface{}{
+		"old_manifests":        oldManifests,
+		"new_manifests":        newManifests,
+		"ignore_hash_suffixes": ignoreHashSuffixes,
+	})
+}
+
+func getBuildDiffTestIDs(d *schema.ResourceData, k string) (ids []string) {
+	for _, id := range d.Get(k).(*schema.Set).List() {
+		ids = append(ids, id.(string))
+	}
+
+	return ids
+}
+
+func TestKustomizationBuildDiff(t *testing.T) {
+	d := getBuildDiffTestData(t, map[string]interface{}{
+		"_/Namespace/_/test":              `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "test"}}`,
+		"_/ConfigMap/test/removed":        `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "removed", "namespace": "test"}}`,
+		"apps/Deployment/test/test":       `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "test", "namespace": "test", "labels": {"app": "test"}}, "spec": {"replicas": 1, "template": {"spec": {"containers": [{"name": "test", "image": "nginx"}]}}}}`,
+		"_/ServiceAccount/test/unchanged": `{"apiVersion": "v1", "kind": "ServiceAccount", "metadata": {"name": "unchanged", "namespace": "test"}}`,
+	}, map[string]interface{}{
+		"_/Namespace/_/test":              "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: test\n",
+		"_/ConfigMap/test/added":          `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "added", "namespace": "test"}}`,
+		"apps/Deployment/test/test":       `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "test", "namespace": "test"}, "spec": {"replicas": 2, "template": {"spec": {"containers": [{"name": "test", "image": "nginx"}, {"name": "sidecar", "image": "envoy"}]}}}}`,
+		"_/ServiceAccount/test/unchanged": `{"apiVersion": "v1", "kind": "ServiceAccount", "metadata": {"name": "unchanged", "namespace": "test"}}`,
+	}, false)
+
+	err := kustomizationBuildDiff(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, []string{"_/ConfigMap/test/added"}, getBuildDiffTestIDs(d, "added_ids"), nil)
+	assert.Equal(t, []string{"_/ConfigMap/test/removed"}, getBuildDiffTestIDs(d, "removed_ids"), nil)
+	assert.Equal(t, []string{"apps/Deployment/test/test"}, getBuildDiffTestIDs(d, "changed_ids"), nil)
+	assert.Equal(t, map[string]interface{}{
+		"apps/Deployment/test/test": `- metadata.labels: {"app":"test"}
+~ spec.replicas: 1 -> 2
++ spec.template.spec.containers[1]: {"image":"envoy","name":"sidecar"}
+`,
+	}, d.Get("diffs"), nil)
+}
+
+func TestKustomizationBuildDiffHashSuffixes(t *testing.T) {
+	oldManifests := map[string]interface{}{
+		"_/ConfigMap/test/test-2k6b7bh7gc": `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "test-2k6b7bh7gc", "namespace": "test"}, "data": {"key": "initial"}}`,
+		"apps/Deployment/test/test":        buildDiffTestDeploymentJSON("test-2k6b7bh7gc"),
+	}
+	newManifests := map[string]interface{}{
+		"_/ConfigMap/test/test-5f8h9dtm6k": `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "test-5f8h9dtm6k", "namespace": "test"}, "data": {"key": "modified"}}`,
+		"apps/Deployment/test/test":        buildDiffTestDeploymentJSON("test-5f8h9dtm6k"),
+	}
+
+	// without ignoring the suffixes, the ConfigMap is replaced and the reference changes
+	d := getBuildDiffTestData(t, oldManifests, newManifests, false)
+	err := kustomizationBuildDiff(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, []string{"_/ConfigMap/test/test-5f8h9dtm6k"}, getBuildDiffTestIDs(d, "added_ids"), nil)
+	assert.Equal(t, []string{"_/ConfigMap/test/test-2k6b7bh7gc"}, getBuildDiffTestIDs(d, "removed_ids"), nil)
+	assert.Equal(t, []string{"apps/Deployment/test/test"}, getBuildDiffTestIDs(d, "changed_ids"), nil)
+
+	// ignoring the suffixes, only the ConfigMap's data changes
+	d = getBuildDiffTestData(t, oldManifests, newManifests, true)
+	err = kustomizationBuildDiff(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, []string(nil), getBuildDiffTestIDs(d, "added_ids"), nil)
+	assert.Equal(t, []string(nil), getBuildDiffTestIDs(d, "removed_ids"), nil)
+	assert.Equal(t, []string{"_/ConfigMap/test/test-5f8h9dtm6k"}, getBuildDiffTestIDs(d, "changed_ids"), nil)
+	assert.Equal(t, map[string]interface{}{
+		"_/ConfigMap/test/test-5f8h9dtm6k": "~ data.key: \"initial\" -> \"modified\"\n",
+	}, d.Get("diffs"), nil)
+}
+
+func TestKustomizationBuildDiffHashSuffixCollision(t *testing.T) {
+	manifests := map[string]interface{}{
+		"_/ConfigMap/test/test-2k6b7bh7gc": `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "test-2k6b7bh7gc", "namespace": "test"}}`,
+		"_/ConfigMap/test/test-5f8h9dtm6k": `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "test-5f8h9dtm6k", "namespace": "test"}}`,
+	}
+
+	d := getBuildDiffTestData(t, map[string]interface{}{}, manifests, true)
+	err := kustomizationBuildDiff(d, &Config{})
+	assert.Equal(t, `kustomizationBuildDiff: new_manifests: "_/ConfigMap/test/test-2k6b7bh7gc" and "_/ConfigMap/test/test-5f8h9dtm6k" have the same ID "_/ConfigMap/test/test" without hash suffixes`, err.Error(), nil)
+
+	// without ignoring the suffixes, both are added
+	d = getBuildDiffTestData(t, map[string]interface{}{}, manifests, false)
+	err = kustomizationBuildDiff(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 2, len(getBuildDiffTestIDs(d, "added_ids")), nil)
+}
+
+func TestKustomizationBuildDiffSecret(t *testing.T) {
+	d := getBuildDiffTestData(t, map[string]interface{}{
+		"_/Secret/test/test": `{"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "test", "namespace": "test"}, "data": {"password": "b2xk", "username": "dXNlcg=="}}`,
+	}, map[string]interface{}{
+		"_/Secret/test/test": `{"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "test", "namespace": "test"}, "data": {"password": "bmV3", "username": "dXNlcg=="}, "stringData": {"token": "secret"}}`,
+	}, false)
+
+	err := kustomizationBuildDiff(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, map[string]interface{}{
+		"_/Secret/test/test": `~ data.password: "*** (before)" -> "*** (after)"
++ stringData: {"token":"***"}
+`,
+	}, d.Get("diffs"), nil)
+}
+
+func buildDiffTestDeploymentJSON(configMap string) string {
+	return fmt.Sprintf(buildDiffTestDeployment, configMap)
+}
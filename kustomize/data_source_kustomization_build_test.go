@@ -1,10 +1,14 @@
 package kustomize
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/krusty"
 )
 
 func TestAccDataSourceKustomization_basic(t *testing.T) {
@@ -108,3 +112,135 @@ output "deployment" {
 }
 `, path)
 }
+
+func TestAccDataSourceKustomization_files(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		//PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceKustomizationConfig_files(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.kustomization_build.test", "id"),
+					resource.TestCheckResourceAttr("data.kustomization_build.test", "path", "overlay"),
+					resource.TestCheckResourceAttr("data.kustomization_build.test", "files.%", "4"),
+					resource.TestCheckResourceAttr("data.kustomization_build.test", "ids.#", "2"),
+					resource.TestCheckResourceAttr("data.kustomization_build.test", "ids_prio.#", "3"),
+					resource.TestCheckResourceAttr("data.kustomization_build.test", "manifests.%", "2"),
+					resource.TestCheckOutput("configmap", "{\"apiVersion\":\"v1\",\"data\":{\"key\":\"overlay\"},\"kind\":\"ConfigMap\",\"metadata\":{\"name\":\"test\",\"namespace\":\"test-files\"}}"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceKustomizationConfig_files() string {
+	return `
+data "kustomization_build" "test" {
+	path = "overlay"
+
+	files = {
+		"base/kustomization.yaml" = <<-EOT
+		resources:
+		- namespace.yaml
+		- configmap.yaml
+		EOT
+		"base/namespace.yaml" = <<-EOT
+		apiVersion: v1
+		kind: Namespace
+		metadata:
+		  name: test-files
+		EOT
+		"base/configmap.yaml" = <<-EOT
+		apiVersion: v1
+		kind: ConfigMap
+		metadata:
+		  name: test
+		  namespace: test-files
+		data:
+		  key: base
+		EOT
+		"overlay/kustomization.yaml" = <<-EOT
+		resources:
+		- ../base
+		patches:
+		- patch: |-
+		    apiVersion: v1
+		    kind: ConfigMap
+		    metadata:
+		      name: test
+		      namespace: test-files
+		    data:
+		      key: overlay
+		EOT
+	}
+}
+
+output "configmap" {
+	value = data.kustomization_build.test.manifests["_/ConfigMap/test-files/test"]
+}
+`
+}
+
+func TestKustomizationBuildInMemoryFS(t *testing.T) {
+	files := map[string]string{
+		"base/kustomization.yaml": "resources:\n- configmap.yaml\n",
+		"base/configmap.yaml":     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n",
+		"overlay/kustomization.yaml": "resources:\n- ../base\n- ../base/configmap.yaml\n" +
+			"namePrefix: test-\n",
+		"kustomization.yaml": "resources:\n- base\n",
+	}
+
+	fSys, err := makeInMemoryFS(files)
+	assert.Equal(t, nil, err, nil)
+
+	// the root of the virtual tree
+	rm, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fSys, ".")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 1, rm.Size(), nil)
+
+	// load restrictions apply within the virtual tree
+	_, err = krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fSys, "overlay")
+	assert.NotEqual(t, nil, err, nil)
+	assert.Contains(t, err.Error(), "is not in or below", nil)
+
+	// files on disk are not available
+	_, err = krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fSys, "test_kustomizations/basic/initial")
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func TestKustomizationBuildInMemoryFSInvalidFileName(t *testing.T) {
+	for _, name := range []string{
+		"../kustomization.yaml",
+		"/kustomization.yaml",
+		"base/../../kustomization.yaml",
+	} {
+		_, err := makeInMemoryFS(map[string]string{name: ""})
+		assert.NotEqual(t, nil, err, name)
+	}
+}
+
+// Test data of the build data source, building the Kustomization
+// in path, with the other arguments in raw.
+func getBuildTestData(t *testing.T, path string, raw map[string]interface{}) *schema.ResourceData {
+	r := map[string]interface{}{"path": path}
+	for k, v := range raw {
+		r[k] = v
+	}
+
+	return schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, r)
+}
+
+// Decodes a map of IDs to JSON encoded lists, e.g. validation_errors.
+func getBuildTestJSONLists(t *testing.T, d *schema.ResourceData, key string) map[string][]string {
+	lists := make(map[string][]string)
+	for id, j := range d.Get(key).(map[string]interface{}) {
+		var l []string
+		err := json.Unmarshal([]byte(j.(string)), &l)
+		assert.Equal(t, nil, err, nil)
+		lists[id] = l
+	}
+
+	return lists
+}
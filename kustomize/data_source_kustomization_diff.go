@@ -0,0 +1,256 @@
+package kustomize
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pmezard/go-difflib/difflib"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sdynamic "k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	manifestMissing   = "missing"
+	manifestIdentical = "identical"
+	manifestChanged   = "changed"
+)
+
+func dataSourceKustomizationDiff() *schema.Resource {
+	return &schema.Resource{
+		Read: kustomizationDiff,
+
+		Schema: map[string]*schema.Schema{
+			"manifests": &schema.Schema{
+				Type:     schema.TypeMap,
+				Required: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"missing_ids": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      idSetHash,
+			},
+			"identical_ids": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      idSetHash,
+			},
+			"changed_ids": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      idSetHash,
+			},
+			"diffs": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+// Compares the manifests to the cluster, the way kustomization_resource
+// would update them, by dry-running the same patch.
+func kustomizationDiff(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Config).Client
+	mapper := m.(*Config).Mapper
+	gzipLastAppliedConfig := m.(*Config).GzipLastAppliedConfig
+
+	manifests := d.Get("manifests").(map[string]interface{})
+
+	ids := make([]string, 0, len(manifests))
+	for id := range manifests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	missing := []string{}
+	identical := []string{}
+	changed := []string{}
+	diffs := make(map[string]string)
+	for _, id := range ids {
+		state, diff, err := getManifestDiff(client, mapper, gzipLastAppliedConfig, id, manifests[id].(string))
+		if err != nil {
+			return fmt.Errorf("kustomizationDiff: %q: %s", id, err)
+		}
+
+		switch state {
+		case manifestMissing:
+			missing = append(missing, id)
+		case manifestIdentical:
+			identical = append(identical, id)
+		case manifestChanged:
+			changed = append(changed, id)
+			diffs[id] = diff
+		}
+	}
+
+	d.Set("missing_ids", missing)
+	d.Set("identical_ids", identical)
+	d.Set("changed_ids", changed)
+	d.Set("diffs", diffs)
+	d.SetId(getManifestsID(manifests))
+
+	return nil
+}
+
+// Returns whether the object is missing, identical or changed, and for
+// changed objects the unified diff between the live object and the result
+// of dry-running the patch kustomization_resource would apply.
+func getManifestDiff(client k8sdynamic.Interface, mapper *restmapper.DeferredDiscoveryRESTMapper, gzipLastAppliedConfig bool, id string, body string) (state string, diff string, err error) {
+	kmm := newKManifest(mapper, client)
+	err = kmm.load([]byte(body))
+	if err != nil {
+		return "", "", err
+	}
+	setLastAppliedConfig(kmm, gzipLastAppliedConfig)
+
+	_, err = kmm.mapping()
+	if err != nil {
+		// kinds the cluster doesn't serve, e.g. of CRDs
+		// that don't exist yet, can't have objects either
+		if k8smeta.IsNoMatchError(err) {
+			return manifestMissing, "", nil
+		}
+		return "", "", err
+	}
+
+	current, err := kmm.apiGet(k8smetav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return manifestMissing, "", nil
+		}
+		return "", "", err
+	}
+
+	// like kustomization_resource, the last applied
+	// configuration is the original of the patch
+	kmo := newKManifest(mapper, client)
+	lac := getLastAppliedConfig(current, gzipLastAppliedConfig)
+	if lac == "" {
+		kmo.json = []byte("{}")
+	} else {
+		kmo.json = []byte(lac)
+	}
+
+	cj, err := current.MarshalJSON()
+	if err != nil {
+		return "", "", err
+	}
+
+	pt, p, err := getPatch(kmm.gvk(), kmo.json, kmm.json, cj)
+	if err != nil {
+		return "", "", err
+	}
+
+	merged, err := kmm.apiPatch(pt, p, k8smetav1.PatchOptions{DryRun: []string{k8smetav1.DryRunAll}})
+	if err != nil {
+		return "", "", err
+	}
+
+	diff, err = getUnifiedDiff(id, current, merged)
+	if err != nil {
+		return "", "", err
+	}
+
+	if diff == "" {
+		return manifestIdentical, "", nil
+	}
+
+	return manifestChanged, diff, nil
+}
+
+// Renders the difference between two objects as a unified diff of their
+// YAML. Fields the API server updates on every write, and the last
+// applied configuration, are ignored. Secret values are masked.
+func getUnifiedDiff(id string, from *k8sunstructured.Unstructured, to *k8sunstructured.Unstructured) (string, error) {
+	from = from.DeepCopy()
+	to = to.DeepCopy()
+	if from.GroupVersionKind().Group == "" && from.GetKind() == "Secret" {
+		maskSecretValues(from.Object, to.Object)
+	}
+
+	a, err := getDiffYAML(from)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := getDiffYAML(to)
+	if err != nil {
+		return "", err
+	}
+
+	if a == b {
+		return "", nil
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(b),
+		FromFile: "live/" + id,
+		ToFile:   "planned/" + id,
+		Context:  3,
+	})
+}
+
+func getDiffYAML(u *k8sunstructured.Unstructured) (string, error) {
+	u = u.DeepCopy()
+	u.SetManagedFields(nil)
+	u.SetResourceVersion("")
+	u.SetGeneration(0)
+
+	annotations := u.GetAnnotations()
+	delete(annotations, lastAppliedConfigAnnotation)
+	delete(annotations, gzipLastAppliedConfigAnnotation)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	u.SetAnnotations(annotations)
+
+	y, err := yaml.Marshal(u.Object)
+	if err != nil {
+		return "", err
+	}
+
+	return string(y), nil
+}
+
+// Like kubectl diff, replaces the values of a Secret's data and
+// stringData with placeholders that only show if a value changed.
+func maskSecretValues(from map[string]interface{}, to map[string]interface{}) {
+	for _, f := range []string{"data", "stringData"} {
+		a, _ := from[f].(map[string]interface{})
+		b, _ := to[f].(map[string]interface{})
+
+		for k, av := range a {
+			bv, ok := b[k]
+			switch {
+			case !ok:
+				a[k] = "***"
+			case reflect.DeepEqual(av, bv):
+				a[k] = "***"
+				b[k] = "***"
+			default:
+				a[k] = "*** (before)"
+				b[k] = "*** (after)"
+			}
+		}
+
+		for k := range b {
+			if _, ok := a[k]; !ok {
+				b[k] = "***"
+			}
+		}
+	}
+}
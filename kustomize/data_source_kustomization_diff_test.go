@@ -0,0 +1,142 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/stretchr/testify/assert"
+
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestAccDataSourceKustomizationDiff_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		//PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceKustomizationDiffConfig_basic("test_kustomizations/basic/initial"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_diff.build", "missing_ids.#", "1"),
+					resource.TestCheckTypeSetElemAttr("data.kustomization_diff.build", "missing_ids.*", "networking.k8s.io/Ingress/test-basic/test"),
+					resource.TestCheckResourceAttr("data.kustomization_diff.build", "identical_ids.#", "3"),
+					resource.TestCheckResourceAttr("data.kustomization_diff.build", "changed_ids.#", "0"),
+					resource.TestCheckResourceAttr("data.kustomization_diff.build", "diffs.%", "0"),
+					resource.TestCheckResourceAttr("data.kustomization_diff.changed", "changed_ids.#", "1"),
+					resource.TestCheckResourceAttrSet("data.kustomization_diff.changed", "diffs._/Namespace/_/test-basic"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceKustomizationDiffConfig_basic(path string) string {
+	return testAccResourceKustomizationConfig_basicInitial(path) + `
+data "kustomization_diff" "build" {
+	manifests = data.kustomization_build.test.manifests
+
+	depends_on = [
+		kustomization_resource.ns,
+		kustomization_resource.svc,
+		kustomization_resource.dep1,
+	]
+}
+
+data "kustomization_diff" "changed" {
+	manifests = {
+		"_/Namespace/_/test-basic" = jsonencode({
+			apiVersion = "v1"
+			kind       = "Namespace"
+			metadata = {
+				name = "test-basic"
+				labels = {
+					"test-diff" = "changed"
+				}
+			}
+		})
+	}
+
+	depends_on = [kustomization_resource.ns]
+}
+`
+}
+
+func TestGetUnifiedDiff(t *testing.T) {
+	live := &k8sunstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":            "test",
+			"namespace":       "test",
+			"resourceVersion": "1",
+			"annotations": map[string]interface{}{
+				lastAppliedConfigAnnotation: "{}",
+			},
+		},
+		"data": map[string]interface{}{
+			"key": "initial",
+		},
+	}}
+
+	planned := live.DeepCopy()
+	planned.SetResourceVersion("2")
+	planned.SetAnnotations(map[string]string{lastAppliedConfigAnnotation: `{"data":{"key":"modified"}}`})
+
+	diff, err := getUnifiedDiff("_/ConfigMap/test/test", live, planned)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "", diff, nil)
+
+	k8sunstructured.SetNestedField(planned.Object, "modified", "data", "key")
+
+	diff, err = getUnifiedDiff("_/ConfigMap/test/test", live, planned)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, `--- live/_/ConfigMap/test/test
++++ planned/_/ConfigMap/test/test
+@@ -1,6 +1,6 @@
+ apiVersion: v1
+ data:
+-  key: initial
++  key: modified
+ kind: ConfigMap
+ metadata:
+   name: test
+`, diff, nil)
+}
+
+func TestGetUnifiedDiffSecret(t *testing.T) {
+	live := &k8sunstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name":      "test",
+			"namespace": "test",
+		},
+		"data": map[string]interface{}{
+			"password": "b2xk",
+			"username": "dXNlcg==",
+		},
+	}}
+
+	planned := live.DeepCopy()
+	k8sunstructured.SetNestedField(planned.Object, "bmV3", "data", "password")
+	k8sunstructured.SetNestedField(planned.Object, "dG9rZW4=", "data", "token")
+
+	diff, err := getUnifiedDiff("_/Secret/test/test", live, planned)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, `--- live/_/Secret/test/test
++++ planned/_/Secret/test/test
+@@ -1,6 +1,7 @@
+ apiVersion: v1
+ data:
+-  password: '*** (before)'
++  password: '*** (after)'
++  token: '***'
+   username: '***'
+ kind: Secret
+ metadata:
+`, diff, nil)
+
+	// the objects are not modified
+	assert.Equal(t, "b2xk", live.Object["data"].(map[string]interface{})["password"], nil)
+}
@@ -0,0 +1,214 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	k8sdynamic "k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+func dataSourceKustomizationLive() *schema.Resource {
+	return &schema.Resource{
+		Read: kustomizationLive,
+
+		Schema: map[string]*schema.Schema{
+			"object_id": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"object_id", "kind"},
+			},
+			"group": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"object_id"},
+			},
+			"kind": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"object_id", "kind"},
+			},
+			"namespace": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"object_id"},
+			},
+			"label_selector": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"object_id"},
+			},
+			"outputs": getOutputsSchema(),
+			"ids": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"manifests": &schema.Schema{
+				Type:      schema.TypeMap,
+				Computed:  true,
+				Sensitive: true,
+				Elem:      &schema.Schema{Type: schema.TypeString},
+			},
+			"manifest": &schema.Schema{
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"output_values": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"objects": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"output_values": {
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func kustomizationLive(d *schema.ResourceData, m interface{}) error {
+	client := m.(*Config).Client
+	mapper := m.(*Config).Mapper
+
+	var objs []k8sunstructured.Unstructured
+	var id string
+	if oid := d.Get("object_id").(string); oid != "" {
+		k, err := parseProviderId(oid)
+		if err != nil {
+			return fmt.Errorf("kustomizationLive: %s", err)
+		}
+
+		u, err := getLiveObject(client, mapper, k)
+		if err != nil {
+			return fmt.Errorf("kustomizationLive: %q: %s", oid, err)
+		}
+
+		objs = append(objs, *u)
+		id = k.string()
+	} else {
+		group := d.Get("group").(string)
+		kind := d.Get("kind").(string)
+		namespace := d.Get("namespace").(string)
+		selector := d.Get("label_selector").(string)
+
+		var err error
+		objs, err = listLiveObjects(client, mapper, group, kind, namespace, selector)
+		if err != nil {
+			return fmt.Errorf("kustomizationLive: %s", err)
+		}
+
+		id = fmt.Sprintf("%s?%s", kManifestId{group: group, kind: kind, namespace: namespace}.string(), selector)
+	}
+
+	outputs := d.Get("outputs").(map[string]interface{})
+
+	var ids []string
+	manifests := make(map[string]string)
+	var objects []interface{}
+	for i := range objs {
+		u := &objs[i]
+
+		// managed fields are large and rarely useful
+		u.SetManagedFields(nil)
+
+		kr := kManifestId{
+			group:     u.GroupVersionKind().Group,
+			kind:      u.GetKind(),
+			namespace: u.GetNamespace(),
+			name:      u.GetName(),
+		}
+
+		j, err := u.MarshalJSON()
+		if err != nil {
+			return fmt.Errorf("kustomizationLive: %q: %s", kr.string(), err)
+		}
+
+		values, err := getOutputValues(u, outputs)
+		if err != nil {
+			return fmt.Errorf("kustomizationLive: %q: %s", kr.string(), err)
+		}
+
+		ids = append(ids, kr.string())
+		manifests[kr.string()] = string(j)
+		objects = append(objects, map[string]interface{}{
+			"id":            kr.string(),
+			"output_values": values,
+		})
+
+		if d.Get("object_id").(string) != "" {
+			d.Set("manifest", string(j))
+			d.Set("output_values", values)
+		}
+	}
+
+	d.Set("ids", ids)
+	d.Set("manifests", manifests)
+	d.Set("objects", objects)
+	d.SetId(id)
+
+	return nil
+}
+
+func getLiveObject(client k8sdynamic.Interface, mapper *restmapper.DeferredDiscoveryRESTMapper, k *kManifestId) (*k8sunstructured.Unstructured, error) {
+	mapping, err := mapper.RESTMapping(k8sschema.GroupKind{Group: k.group, Kind: k.kind})
+	if err != nil {
+		return nil, fmt.Errorf("api error: %s", err)
+	}
+
+	var ri k8sdynamic.ResourceInterface = client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == k8smeta.RESTScopeNameNamespace {
+		ri = client.Resource(mapping.Resource).Namespace(k.namespace)
+	}
+
+	return ri.Get(context.TODO(), k.name, k8smetav1.GetOptions{})
+}
+
+// Lists the objects of a kind, in all namespaces if namespace is empty.
+func listLiveObjects(client k8sdynamic.Interface, mapper *restmapper.DeferredDiscoveryRESTMapper, group string, kind string, namespace string, selector string) ([]k8sunstructured.Unstructured, error) {
+	mapping, err := mapper.RESTMapping(k8sschema.GroupKind{Group: group, Kind: kind})
+	if err != nil {
+		return nil, fmt.Errorf("api error: %s", err)
+	}
+
+	var ri k8sdynamic.ResourceInterface = client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == k8smeta.RESTScopeNameNamespace && namespace != "" {
+		ri = client.Resource(mapping.Resource).Namespace(namespace)
+	}
+
+	resp, err := ri.List(context.TODO(), k8smetav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	objs := resp.Items
+	sort.Slice(objs, func(i, j int) bool {
+		if objs[i].GetNamespace() == objs[j].GetNamespace() {
+			return objs[i].GetName() < objs[j].GetName()
+		}
+		return objs[i].GetNamespace() < objs[j].GetNamespace()
+	})
+
+	return objs, nil
+}
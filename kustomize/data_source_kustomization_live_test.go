@@ -0,0 +1,56 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceKustomizationLive_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		//PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceKustomizationLiveConfig_basic("test_kustomizations/basic/initial"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_live.svc", "id", "_/Service/test-basic/test"),
+					resource.TestCheckResourceAttr("data.kustomization_live.svc", "ids.#", "1"),
+					resource.TestCheckResourceAttrSet("data.kustomization_live.svc", "manifest"),
+					resource.TestCheckResourceAttrSet("data.kustomization_live.svc", "output_values.cluster_ip"),
+					resource.TestCheckResourceAttr("data.kustomization_live.deployments", "ids.#", "1"),
+					resource.TestCheckResourceAttr("data.kustomization_live.deployments", "ids.0", "apps/Deployment/test-basic/test"),
+					resource.TestCheckResourceAttr("data.kustomization_live.deployments", "objects.0.output_values.image", "nginx"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceKustomizationLiveConfig_basic(path string) string {
+	return testAccResourceKustomizationConfig_basicInitial(path) + `
+data "kustomization_live" "svc" {
+	object_id = "_/Service/test-basic/test"
+
+	outputs = {
+		cluster_ip = ".spec.clusterIP"
+	}
+
+	depends_on = [kustomization_resource.svc]
+}
+
+data "kustomization_live" "deployments" {
+	group          = "apps"
+	kind           = "Deployment"
+	namespace      = "test-basic"
+	label_selector = "app=test"
+
+	outputs = {
+		image = ".spec.template.spec.containers[0].image"
+	}
+
+	depends_on = [kustomization_resource.dep1]
+}
+`
+}
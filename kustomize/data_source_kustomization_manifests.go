@@ -0,0 +1,60 @@
+package kustomize
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"sigs.k8s.io/kustomize/api/provider"
+	"sigs.k8s.io/kustomize/api/resmap"
+)
+
+func dataSourceKustomizationManifests() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: readWithAPIDeprecationWarnings(kustomizationManifests),
+
+		Schema: withGeneratedAttributesSchema(map[string]*schema.Schema{
+			"content": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"include":             getFilterSchema(),
+			"exclude":             getFilterSchema(),
+			"priority_defaults":   getPriorityDefaultsSchema(),
+			"priority_rules":      getPriorityRulesSchema(),
+			"guardrails":          getGuardrailsSchema(),
+			"policy":              getPolicySchema(),
+			"schema_validation":   getSchemaValidationSchema(),
+			"admission_policies":  getAdmissionPoliciesSchema(),
+			"target_kube_version": getTargetKubeVersionSchema(),
+		}),
+	}
+}
+
+// Parses a YAML or JSON stream of manifests, without running kustomize.
+func kustomizationManifests(d *schema.ResourceData, m interface{}) error {
+	content := d.Get("content").(string)
+
+	rmF := resmap.NewFactory(provider.NewDefaultDepProvider().GetResourceFactory())
+	rm, err := rmF.NewResMapFromBytes([]byte(content))
+	if err != nil {
+		return fmt.Errorf("kustomizationManifests: %s", err)
+	}
+
+	err = applyFilters(d, rm)
+	if err != nil {
+		return fmt.Errorf("kustomizationManifests: %s", err)
+	}
+
+	err = enforceGuardrails(d, rm, m.(*Config).Guardrails)
+	if err != nil {
+		return fmt.Errorf("kustomizationManifests: %s", err)
+	}
+
+	err = enforcePolicies(d, rm)
+	if err != nil {
+		return fmt.Errorf("kustomizationManifests: %s", err)
+	}
+
+	return setGeneratedAttributes(d, rm)
+}
@@ -0,0 +1,85 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+const testManifestsContent = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: test-manifests
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: test-manifests
+data:
+  key: value
+---
+{"apiVersion": "admissionregistration.k8s.io/v1", "kind": "ValidatingWebhookConfiguration", "metadata": {"name": "test"}}
+`
+
+func TestAccDataSourceKustomizationManifests_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		//PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceKustomizationManifestsConfig_basic(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.kustomization_manifests.test", "id"),
+					resource.TestCheckResourceAttr("data.kustomization_manifests.test", "ids.#", "3"),
+					resource.TestCheckResourceAttr("data.kustomization_manifests.test", "ids_prio.#", "3"),
+					resource.TestCheckResourceAttr("data.kustomization_manifests.test", "manifests.%", "3"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceKustomizationManifestsConfig_basic() string {
+	return `
+data "kustomization_manifests" "test" {
+	content = <<-EOT
+` + testManifestsContent + `	EOT
+}
+`
+}
+
+func TestKustomizationManifests(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, dataSourceKustomizationManifests().Schema, map[string]interface{}{
+		"content": testManifestsContent,
+	})
+
+	err := kustomizationManifests(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+
+	ids := d.Get("ids").(*schema.Set).List()
+	assert.ElementsMatch(t, []interface{}{
+		"_/Namespace/_/test-manifests",
+		"_/ConfigMap/test-manifests/test",
+		"admissionregistration.k8s.io/ValidatingWebhookConfiguration/_/test",
+	}, ids, nil)
+
+	idsPrio := d.Get("ids_prio").([]interface{})
+	assert.Equal(t, 3, len(idsPrio), nil)
+	assert.ElementsMatch(t, []interface{}{"_/Namespace/_/test-manifests"}, idsPrio[0].(*schema.Set).List(), nil)
+	assert.ElementsMatch(t, []interface{}{"_/ConfigMap/test-manifests/test"}, idsPrio[1].(*schema.Set).List(), nil)
+
+	manifests := d.Get("manifests").(map[string]interface{})
+	assert.Equal(t, `{"apiVersion":"v1","data":{"key":"value"},"kind":"ConfigMap","metadata":{"name":"test","namespace":"test-manifests"}}`, manifests["_/ConfigMap/test-manifests/test"], nil)
+
+	// duplicate IDs are an error
+	d = schema.TestResourceDataRaw(t, dataSourceKustomizationManifests().Schema, map[string]interface{}{
+		"content": testManifestsContent + "---\n" + testManifestsContent,
+	})
+
+	err = kustomizationManifests(d, &Config{})
+	assert.NotEqual(t, nil, err, nil)
+}
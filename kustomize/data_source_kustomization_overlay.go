@@ -6,6 +6,8 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
@@ -130,7 +132,7 @@ func getPatchOptions(o map[string]interface{}) map[string]bool {
 
 func dataSourceKustomizationOverlay() *schema.Resource {
 	return &schema.Resource{
-		Read: kustomizationOverlay,
+		ReadContext: readWithAPIDeprecationWarnings(kustomizationOverlay),
 
 		// support almost all attributes available in a Kustomization
 		//
@@ -138,7 +140,7 @@ func dataSourceKustomizationOverlay() *schema.Resource {
 		// Bases (deprecated)
 		// Configurations (incompatible with plugins)
 		// Validators (requires alpha plugins enabled, we only enable built-in plugins)
-		Schema: map[string]*schema.Schema{
+		Schema: withGeneratedAttributesSchema(map[string]*schema.Schema{
 			"common_annotations": &schema.Schema{
 				Type:     schema.TypeMap,
 				Optional: true,
@@ -299,38 +301,7 @@ func dataSourceKustomizationOverlay() *schema.Resource {
 							Type:     schema.TypeList,
 							Optional: true,
 							MaxItems: 1,
-							Elem: &schema.Resource{
-								Schema: map[string]*schema.Schema{
-									"group": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"version": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"kind": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"name": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"namespace": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"label_selector": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"annotation_selector": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-								},
-							},
+							Elem:     getSelectorSchema(),
 						},
 					},
 				},
@@ -439,6 +410,30 @@ func dataSourceKustomizationOverlay() *schema.Resource {
 					Type: schema.TypeString,
 				},
 			},
+			"resource_manifests": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"files": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"base_dir": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"cache_key": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"track_origins": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
 			"helm_globals": &schema.Schema{
 				Type:     schema.TypeList,
 				MaxItems: 1,
@@ -627,55 +622,23 @@ func dataSourceKustomizationOverlay() *schema.Resource {
 					},
 				},
 			},
-			"ids": &schema.Schema{
-				Type:     schema.TypeSet,
-				Computed: true,
-				Elem:     &schema.Schema{Type: schema.TypeString},
-				Set:      idSetHash,
-			},
-			"ids_prio": &schema.Schema{
-				Type:     schema.TypeList,
-				Computed: true,
-				Elem: &schema.Schema{
-					Type: schema.TypeSet,
-					Set:  idSetHash,
-				},
-			},
-			"manifests": &schema.Schema{
+			"set":                 getSetSchema(),
+			"include":             getFilterSchema(),
+			"exclude":             getFilterSchema(),
+			"priority_defaults":   getPriorityDefaultsSchema(),
+			"priority_rules":      getPriorityRulesSchema(),
+			"guardrails":          getGuardrailsSchema(),
+			"policy":              getPolicySchema(),
+			"schema_validation":   getSchemaValidationSchema(),
+			"admission_policies":  getAdmissionPoliciesSchema(),
+			"target_kube_version": getTargetKubeVersionSchema(),
+			"origins": &schema.Schema{
 				Type:     schema.TypeMap,
 				Computed: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
-			"kustomize_options": &schema.Schema{
-				Type:     schema.TypeList,
-				Optional: true,
-				MaxItems: 1,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"load_restrictor": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-						"enable_alpha_plugins": {
-							Type:     schema.TypeBool,
-							Optional: true,
-						},
-						"enable_exec": {
-							Type:     schema.TypeBool,
-							Optional: true,
-						},
-						"enable_helm": {
-							Type:     schema.TypeBool,
-							Optional: true,
-						},
-						"helm_path": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-					},
-				},
-			},
-		},
+			"kustomize_options": getKustomizeOptionsSchema(),
+		}),
 	}
 }
 
@@ -862,14 +825,7 @@ func getKustomization(d *schema.ResourceData) (k types.Kustomization) {
 			)
 
 			if len(t) > 0 {
-				kp.Target = &types.Selector{}
-				kp.Target.Group = t["group"]
-				kp.Target.Version = t["version"]
-				kp.Target.Kind = t["kind"]
-				kp.Target.Name = t["name"]
-				kp.Target.Namespace = t["namespace"]
-				kp.Target.AnnotationSelector = t["annotation_selector"]
-				kp.Target.LabelSelector = t["label_selector"]
+				kp.Target = getSelector(t)
 			}
 			o := p["options"].([]interface{})
 			if len(o) == 1 && o[0] != nil {
@@ -987,6 +943,13 @@ func getKustomization(d *schema.ResourceData) (k types.Kustomization) {
 		)
 	}
 
+	if d.Get("resource_manifests") != nil {
+		rms := d.Get("resource_manifests").([]interface{})
+		for i := range rms {
+			k.Resources = append(k.Resources, getResourceManifestFileName(i))
+		}
+	}
+
 	if d.Get("helm_globals") != nil {
 		hgs := d.Get("helm_globals").([]interface{})
 
@@ -1107,13 +1070,66 @@ func getKustomization(d *schema.ResourceData) (k types.Kustomization) {
 	return k
 }
 
-func refuseExistingKustomization(fSys filesys.FileSystem) error {
-	for _, n := range konfig.RecognizedKustomizationFileNames() {
-		if fSys.Exists(n) {
-			return fmt.Errorf("buildKustomizeOverlay: Can not build dynamic overlay, found %q in working directory.", n)
+// resource_manifests are served as virtual files
+// and included in the Kustomization's resources
+func getResourceManifestFileName(i int) string {
+	return filepath.Join(resourceManifestsDir, fmt.Sprintf("%d.yaml", i))
+}
+
+func getVirtualFiles(d *schema.ResourceData, baseDir string) (files map[string]string, err error) {
+	files = convertMapStringInterfaceToMapStringString(
+		d.Get("files").(map[string]interface{}),
+	)
+
+	// files can't replace the generated Kustomization or resource_manifests
+	rmDir := filepath.Join(baseDir, resourceManifestsDir)
+	for name := range files {
+		p := filepath.Clean(name)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(baseDir, p)
+		}
+
+		for _, kfn := range konfig.RecognizedKustomizationFileNames() {
+			if p == filepath.Join(baseDir, kfn) {
+				return nil, fmt.Errorf("files: %q is reserved for the generated Kustomization", name)
+			}
+		}
+
+		if p == rmDir || strings.HasPrefix(p, rmDir+string(filepath.Separator)) {
+			return nil, fmt.Errorf("files: %q is reserved for resource_manifests", name)
 		}
 	}
-	return nil
+
+	rms := convertListInterfaceToListString(
+		d.Get("resource_manifests").([]interface{}),
+	)
+	for i := range rms {
+		files[getResourceManifestFileName(i)] = rms[i]
+	}
+
+	return files, nil
+}
+
+func getBaseDir(d *schema.ResourceData) (string, error) {
+	dir := d.Get("base_dir").(string)
+	if dir == "" {
+		return os.Getwd()
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !fi.IsDir() {
+		return "", fmt.Errorf("base_dir %q is not a directory", dir)
+	}
+
+	return dir, nil
 }
 
 func kustomizationOverlay(d *schema.ResourceData, m interface{}) error {
@@ -1125,27 +1141,63 @@ func kustomizationOverlay(d *schema.ResourceData, m interface{}) error {
 	ye.Close()
 	data, _ := ioutil.ReadAll(io.Reader(&b))
 
-	fSys, tmp, err := makeOverlayFS(filesys.MakeFsOnDisk())
+	baseDir, err := getBaseDir(d)
+	if err != nil {
+		return fmt.Errorf("buildKustomizeOverlay: %s", err)
+	}
+
+	ofs, tmp, err := makeOverlayFS(filesys.MakeFsOnDisk(), baseDir)
 	defer os.RemoveAll(tmp)
 	if err != nil {
 		return err
 	}
 
-	// error if the current working directory is already a Kustomization
-	err = refuseExistingKustomization(fSys)
+	files, err := getVirtualFiles(d, baseDir)
 	if err != nil {
-		return err
+		return fmt.Errorf("buildKustomizeOverlay: %s", err)
+	}
+
+	fSys, err := makeLayeredFS(ofs, baseDir, files)
+	if err != nil {
+		return fmt.Errorf("buildKustomizeOverlay: %s", err)
 	}
 
-	fSys.WriteFile(KFILENAME, data)
-	defer fSys.RemoveAll(KFILENAME)
+	kfp := filepath.Join(baseDir, KFILENAME)
+	err = fSys.WriteFile(kfp, data)
+	if err != nil {
+		return fmt.Errorf("buildKustomizeOverlay: %s", err)
+	}
+	defer fSys.RemoveAll(kfp)
+
+	kOpts := getMergedKustomizeOptions(d, m.(*Config).KustomizeOptions)
+	stripOrigins := enableOriginAnnotations(d, kOpts)
+
+	rm, err := runCachedKustomizeBuild(m.(*Config).BuildCache, fSys, baseDir, kOpts, d.Get("cache_key").(string), string(data))
+	if err != nil {
+		return fmt.Errorf("buildKustomizeOverlay: %s", err)
+	}
+
+	err = applySetValues(d, rm)
+	if err != nil {
+		return fmt.Errorf("buildKustomizeOverlay: %s", err)
+	}
+
+	err = applyFilters(d, rm)
+	if err != nil {
+		return fmt.Errorf("buildKustomizeOverlay: %s", err)
+	}
+
+	err = enforceGuardrails(d, rm, m.(*Config).Guardrails)
+	if err != nil {
+		return fmt.Errorf("buildKustomizeOverlay: %s", err)
+	}
+
+	err = enforcePolicies(d, rm)
+	if err != nil {
+		return fmt.Errorf("buildKustomizeOverlay: %s", err)
+	}
 
-	// mutex as tmp workaround for upstream bug
-	// https://github.com/kubernetes-sigs/kustomize/issues/3659
-	mu := m.(*Config).Mutex
-	mu.Lock()
-	rm, err := runKustomizeBuild(fSys, ".", d)
-	mu.Unlock()
+	err = setOrigins(d, rm, stripOrigins)
 	if err != nil {
 		return fmt.Errorf("buildKustomizeOverlay: %s", err)
 	}
@@ -1,15 +1,19 @@
 package kustomize
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 
+	"sigs.k8s.io/kustomize/api/konfig"
 	"sigs.k8s.io/kustomize/kyaml/filesys"
 )
 
 var KFILENAME string = "Kustomization"
 
+const resourceManifestsDir = "_resource_manifests"
+
 var _ filesys.FileSystem = overlayFileSystem{}
 
 type overlayFileSystem struct {
@@ -22,18 +26,20 @@ type overlayFileSystem struct {
 // the shared file system prevents parallel execution.
 // This filesys.FileSystem implementation solves this
 // by handling the dynamic Kustomization in a temp directory.
-func makeOverlayFS(fs filesys.FileSystem) (ofs filesys.FileSystem, tmp string, err error) {
+// The virtual Kustomization is placed in dir. Real kustomization files
+// in dir are hidden, so they never collide with the virtual one.
+func makeOverlayFS(fs filesys.FileSystem, dir string) (ofs filesys.FileSystem, tmp string, err error) {
 	tmp, err = ioutil.TempDir("", "terraform-provider-kustomization-*")
 	if err != nil {
 		return ofs, tmp, err
 	}
 	kfpReal := filepath.Join(tmp, KFILENAME)
 
-	cwd, err := os.Getwd()
+	dir, err = filepath.Abs(dir)
 	if err != nil {
 		return ofs, tmp, err
 	}
-	kfpVirt := filepath.Join(cwd, KFILENAME)
+	kfpVirt := filepath.Join(dir, KFILENAME)
 
 	ofs = overlayFileSystem{
 		fs:      fs,
@@ -57,6 +63,10 @@ func (ofs overlayFileSystem) MkdirAll(name string) error {
 }
 
 func (ofs overlayFileSystem) RemoveAll(name string) error {
+	if ofs.isVirtual(name) {
+		return ofs.fs.RemoveAll(ofs.kfpReal)
+	}
+
 	return ofs.fs.RemoveAll(name)
 }
 
@@ -65,14 +75,18 @@ func (ofs overlayFileSystem) ReadDir(name string) ([]string, error) {
 }
 
 func (ofs overlayFileSystem) Open(name string) (filesys.File, error) {
+	if ofs.isHidden(name) {
+		return nil, fmt.Errorf("%q: %s", name, os.ErrNotExist)
+	}
+
 	return ofs.fs.Open(name)
 }
 
 func (ofs overlayFileSystem) CleanedAbs(path string) (filesys.ConfirmedDir, string, error) {
-	if path == ofs.kfpVirt {
+	if ofs.isVirtual(path) {
 		// if the path we're looking for is our virtual Kustomization file
 		// fake a correct CleanedAbs response
-		cd, _, err := ofs.fs.CleanedAbs(".")
+		cd, _, err := ofs.fs.CleanedAbs(filepath.Dir(ofs.kfpVirt))
 		if err != nil {
 			return "", "", err
 		}
@@ -83,9 +97,13 @@ func (ofs overlayFileSystem) CleanedAbs(path string) (filesys.ConfirmedDir, stri
 }
 
 func (ofs overlayFileSystem) Exists(name string) bool {
+	if ofs.isHidden(name) {
+		return false
+	}
+
 	ex := ofs.fs.Exists(name)
 
-	if ex == false && name == ofs.kfpVirt {
+	if ex == false && ofs.isVirtual(name) {
 		return ofs.fs.Exists(ofs.kfpReal)
 	}
 
@@ -101,7 +119,11 @@ func (ofs overlayFileSystem) IsDir(name string) bool {
 }
 
 func (ofs overlayFileSystem) ReadFile(name string) ([]byte, error) {
-	if name == ofs.kfpVirt {
+	if ofs.isHidden(name) {
+		return nil, fmt.Errorf("%q: %s", name, os.ErrNotExist)
+	}
+
+	if ofs.isVirtual(name) {
 		return ofs.fs.ReadFile(ofs.kfpReal)
 	}
 
@@ -109,18 +131,196 @@ func (ofs overlayFileSystem) ReadFile(name string) ([]byte, error) {
 }
 
 func (ofs overlayFileSystem) WriteFile(name string, c []byte) error {
+	if ofs.isVirtual(name) {
+		return ofs.fs.WriteFile(ofs.kfpReal, c)
+	}
+
+	return ofs.fs.WriteFile(name, c)
+}
+
+func (ofs overlayFileSystem) isVirtual(name string) bool {
 	abs, err := filepath.Abs(name)
 	if err != nil {
-		return err
+		return false
 	}
 
-	if abs == ofs.kfpVirt {
-		return ofs.fs.WriteFile(ofs.kfpReal, c)
+	return abs == ofs.kfpVirt
+}
+
+// Other kustomization files next to the virtual one would make kustomize
+// fail with a multiple kustomization files error, so hide them.
+func (ofs overlayFileSystem) isHidden(name string) bool {
+	abs, err := filepath.Abs(name)
+	if err != nil || abs == ofs.kfpVirt {
+		return false
 	}
 
-	return ofs.fs.WriteFile(name, c)
+	if filepath.Dir(abs) != filepath.Dir(ofs.kfpVirt) {
+		return false
+	}
+
+	for _, kfn := range konfig.RecognizedKustomizationFileNames() {
+		if filepath.Base(abs) == kfn {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (ofs overlayFileSystem) Walk(path string, walkFn filepath.WalkFunc) error {
 	return ofs.fs.Walk(path, walkFn)
 }
+
+var _ filesys.FileSystem = layeredFileSystem{}
+
+type layeredFileSystem struct {
+	upper filesys.FileSystem
+	lower filesys.FileSystem
+	root  string
+}
+
+// Serves files defined in Terraform from memory on top of another
+// filesys.FileSystem. Relative file names are anchored to root.
+// Virtual files take precedence over files with the same
+// name in the lower file system, directories are merged.
+func makeLayeredFS(fs filesys.FileSystem, root string, files map[string]string) (lfs filesys.FileSystem, err error) {
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return lfs, err
+	}
+
+	mfs := filesys.MakeFsInMemory()
+
+	for name, content := range files {
+		p := name
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+
+		err = mfs.WriteFile(p, []byte(content))
+		if err != nil {
+			return lfs, err
+		}
+	}
+
+	lfs = layeredFileSystem{
+		upper: mfs,
+		lower: fs,
+		root:  root,
+	}
+
+	return lfs, nil
+}
+
+func (lfs layeredFileSystem) abs(name string) string {
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+
+	return filepath.Join(lfs.root, name)
+}
+
+func (lfs layeredFileSystem) isUpperFile(name string) bool {
+	p := lfs.abs(name)
+	return lfs.upper.Exists(p) && !lfs.upper.IsDir(p)
+}
+
+func (lfs layeredFileSystem) Create(name string) (filesys.File, error) {
+	return lfs.lower.Create(name)
+}
+
+func (lfs layeredFileSystem) Mkdir(name string) error {
+	return lfs.lower.Mkdir(name)
+}
+
+func (lfs layeredFileSystem) MkdirAll(name string) error {
+	return lfs.lower.MkdirAll(name)
+}
+
+func (lfs layeredFileSystem) RemoveAll(name string) error {
+	return lfs.lower.RemoveAll(name)
+}
+
+func (lfs layeredFileSystem) ReadDir(name string) ([]string, error) {
+	lr, lerr := lfs.lower.ReadDir(name)
+	ur, uerr := lfs.upper.ReadDir(lfs.abs(name))
+	if lerr != nil && uerr != nil {
+		return nil, lerr
+	}
+
+	return mergeFileNames(lr, ur), nil
+}
+
+func (lfs layeredFileSystem) Open(name string) (filesys.File, error) {
+	if lfs.isUpperFile(name) {
+		return lfs.upper.Open(lfs.abs(name))
+	}
+
+	return lfs.lower.Open(name)
+}
+
+func (lfs layeredFileSystem) CleanedAbs(path string) (filesys.ConfirmedDir, string, error) {
+	if !lfs.lower.Exists(path) && lfs.upper.Exists(lfs.abs(path)) {
+		return lfs.upper.CleanedAbs(lfs.abs(path))
+	}
+
+	return lfs.lower.CleanedAbs(path)
+}
+
+func (lfs layeredFileSystem) Exists(name string) bool {
+	return lfs.lower.Exists(name) || lfs.upper.Exists(lfs.abs(name))
+}
+
+func (lfs layeredFileSystem) Glob(pattern string) ([]string, error) {
+	lr, err := lfs.lower.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	ur, err := lfs.upper.Glob(lfs.abs(pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeFileNames(lr, ur), nil
+}
+
+func (lfs layeredFileSystem) IsDir(name string) bool {
+	return lfs.lower.IsDir(name) || lfs.upper.IsDir(lfs.abs(name))
+}
+
+func (lfs layeredFileSystem) ReadFile(name string) ([]byte, error) {
+	if lfs.isUpperFile(name) {
+		return lfs.upper.ReadFile(lfs.abs(name))
+	}
+
+	return lfs.lower.ReadFile(name)
+}
+
+func (lfs layeredFileSystem) WriteFile(name string, c []byte) error {
+	return lfs.lower.WriteFile(name, c)
+}
+
+func (lfs layeredFileSystem) Walk(path string, walkFn filepath.WalkFunc) error {
+	if !lfs.lower.Exists(path) && lfs.upper.Exists(lfs.abs(path)) {
+		return lfs.upper.Walk(lfs.abs(path), walkFn)
+	}
+
+	return lfs.lower.Walk(path, walkFn)
+}
+
+func mergeFileNames(a []string, b []string) (r []string) {
+	seen := make(map[string]bool)
+	for _, l := range [][]string{a, b} {
+		for _, n := range l {
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			r = append(r, n)
+		}
+	}
+
+	return r
+}
@@ -70,7 +70,7 @@ func TestOverlayFileSystemCreate(t *testing.T) {
 	name := filepath.Join(tmp, "test-file")
 
 	dfs := filesys.MakeFsOnDisk()
-	ofs, otmp, err := makeOverlayFS(dfs)
+	ofs, otmp, err := makeOverlayFS(dfs, ".")
 	defer os.RemoveAll(otmp)
 	assert.Equal(t, nil, err, nil)
 
@@ -89,7 +89,7 @@ func TestOverlayFileSystemMkdir(t *testing.T) {
 	name := filepath.Join(tmp, "test-mkdir")
 
 	dfs := filesys.MakeFsOnDisk()
-	ofs, otmp, err := makeOverlayFS(dfs)
+	ofs, otmp, err := makeOverlayFS(dfs, ".")
 	defer os.RemoveAll(otmp)
 	assert.Equal(t, nil, err, nil)
 
@@ -108,7 +108,7 @@ func TestOverlayFileSystemMkdirAll(t *testing.T) {
 	name := filepath.Join(tmp, "test-mkdirall")
 
 	dfs := filesys.MakeFsOnDisk()
-	ofs, otmp, err := makeOverlayFS(dfs)
+	ofs, otmp, err := makeOverlayFS(dfs, ".")
 	defer os.RemoveAll(otmp)
 	assert.Equal(t, nil, err, nil)
 
@@ -127,7 +127,7 @@ func TestOverlayFileSystemRemoveAll(t *testing.T) {
 	name := filepath.Join(tmp, "test-mkdirall/test")
 
 	dfs := filesys.MakeFsOnDisk()
-	ofs, otmp, err := makeOverlayFS(dfs)
+	ofs, otmp, err := makeOverlayFS(dfs, ".")
 	defer os.RemoveAll(otmp)
 	assert.Equal(t, nil, err, nil)
 
@@ -147,7 +147,7 @@ func TestOverlayFileSystemOpen(t *testing.T) {
 	name := filepath.Join(tmp, "test")
 
 	dfs := filesys.MakeFsOnDisk()
-	ofs, otmp, err := makeOverlayFS(dfs)
+	ofs, otmp, err := makeOverlayFS(dfs, ".")
 	defer os.RemoveAll(otmp)
 	assert.Equal(t, nil, err, nil)
 
@@ -163,7 +163,7 @@ func TestOverlayFileSystemOpen(t *testing.T) {
 
 func TestOverlayFileSystemCleanedAbs(t *testing.T) {
 	dfs := filesys.MakeFsOnDisk()
-	ofs, otmp, err := makeOverlayFS(dfs)
+	ofs, otmp, err := makeOverlayFS(dfs, ".")
 	defer os.RemoveAll(otmp)
 	assert.Equal(t, nil, err, nil)
 
@@ -200,7 +200,7 @@ func TestOverlayFileSystemExists(t *testing.T) {
 	name := filepath.Join(tmp, "test")
 
 	dfs := filesys.MakeFsOnDisk()
-	ofs, otmp, err := makeOverlayFS(dfs)
+	ofs, otmp, err := makeOverlayFS(dfs, ".")
 	defer os.RemoveAll(otmp)
 	assert.Equal(t, nil, err, nil)
 
@@ -218,7 +218,7 @@ func TestOverlayFileSystemGlob(t *testing.T) {
 	name := filepath.Join(tmp, "test")
 
 	dfs := filesys.MakeFsOnDisk()
-	ofs, otmp, err := makeOverlayFS(dfs)
+	ofs, otmp, err := makeOverlayFS(dfs, ".")
 	defer os.RemoveAll(otmp)
 	assert.Equal(t, nil, err, nil)
 
@@ -234,7 +234,7 @@ func TestOverlayFileSystemIsDir(t *testing.T) {
 	name := "test_kustomizations"
 
 	dfs := filesys.MakeFsOnDisk()
-	ofs, otmp, err := makeOverlayFS(dfs)
+	ofs, otmp, err := makeOverlayFS(dfs, ".")
 	defer os.RemoveAll(otmp)
 	assert.Equal(t, nil, err, nil)
 
@@ -247,7 +247,7 @@ func TestOverlayFileSystemIsDir(t *testing.T) {
 
 func TestOverlayFileSystemReadFile(t *testing.T) {
 	dfs := filesys.MakeFsOnDisk()
-	ofs, otmp, err := makeOverlayFS(dfs)
+	ofs, otmp, err := makeOverlayFS(dfs, ".")
 	defer os.RemoveAll(otmp)
 	assert.Equal(t, nil, err, nil)
 
@@ -275,7 +275,7 @@ func TestOverlayFileSystemReadFile(t *testing.T) {
 
 func TestOverlayFileSystemWriteFile(t *testing.T) {
 	dfs := filesys.MakeFsOnDisk()
-	ofs, otmp, err := makeOverlayFS(dfs)
+	ofs, otmp, err := makeOverlayFS(dfs, ".")
 	defer os.RemoveAll(otmp)
 	assert.Equal(t, nil, err, nil)
 
@@ -308,3 +308,97 @@ func TestOverlayFileSystemWriteFile(t *testing.T) {
 	assert.Equal(t, ed, dd, nil)
 	assert.Equal(t, nil, derr, nil)
 }
+
+func TestLayeredFileSystemReadFile(t *testing.T) {
+	dfs := filesys.MakeFsOnDisk()
+	lfs, err := makeLayeredFS(dfs, ".", map[string]string{
+		"test-virtual/test.yaml": "test",
+	})
+	assert.Equal(t, nil, err, nil)
+
+	cwd, cwderr := os.Getwd()
+	assert.Equal(t, nil, cwderr, nil)
+
+	// virtual files can be read relative to cwd and absolute
+	d, err := lfs.ReadFile("test-virtual/test.yaml")
+	assert.Equal(t, []byte("test"), d, nil)
+	assert.Equal(t, nil, err, nil)
+
+	d, err = lfs.ReadFile(filepath.Join(cwd, "test-virtual/test.yaml"))
+	assert.Equal(t, []byte("test"), d, nil)
+	assert.Equal(t, nil, err, nil)
+
+	// virtual files are not on disk
+	assert.Equal(t, false, dfs.Exists("test-virtual/test.yaml"), nil)
+
+	// files on disk can still be read
+	d, err = lfs.ReadFile("test_kustomizations/basic/initial/namespace.yaml")
+	assert.NotEqual(t, 0, len(d), nil)
+	assert.Equal(t, nil, err, nil)
+}
+
+func TestLayeredFileSystemShadowsDisk(t *testing.T) {
+	dfs := filesys.MakeFsOnDisk()
+	lfs, err := makeLayeredFS(dfs, ".", map[string]string{
+		"test_kustomizations/basic/initial/namespace.yaml": "test",
+	})
+	assert.Equal(t, nil, err, nil)
+
+	d, err := lfs.ReadFile("test_kustomizations/basic/initial/namespace.yaml")
+	assert.Equal(t, []byte("test"), d, nil)
+	assert.Equal(t, nil, err, nil)
+
+	// directories are merged
+	r, err := lfs.ReadDir("test_kustomizations/basic/initial")
+	assert.Equal(t, nil, err, nil)
+	assert.ElementsMatch(t, []string{"kustomization.yaml", "namespace.yaml"}, r, nil)
+}
+
+func TestLayeredFileSystemCleanedAbs(t *testing.T) {
+	dfs := filesys.MakeFsOnDisk()
+	lfs, err := makeLayeredFS(dfs, ".", map[string]string{
+		"test-virtual/test.yaml": "test",
+	})
+	assert.Equal(t, nil, err, nil)
+
+	cwd, cwderr := os.Getwd()
+	assert.Equal(t, nil, cwderr, nil)
+	vpath := filepath.Join(cwd, "test-virtual/test.yaml")
+
+	_, _, derr := dfs.CleanedAbs(vpath)
+	assert.NotEqual(t, nil, derr, nil)
+
+	lcd, ln, lerr := lfs.CleanedAbs(vpath)
+	assert.Equal(t, filesys.ConfirmedDir(filepath.Join(cwd, "test-virtual")), lcd, nil)
+	assert.Equal(t, "test.yaml", ln, nil)
+	assert.Equal(t, nil, lerr, nil)
+
+	assert.Equal(t, true, lfs.Exists("test-virtual"), nil)
+	assert.Equal(t, true, lfs.IsDir("test-virtual"), nil)
+	assert.Equal(t, false, lfs.IsDir("test-virtual/test.yaml"), nil)
+}
+
+func TestOverlayFileSystemHidesKustomizations(t *testing.T) {
+	dfs := filesys.MakeFsOnDisk()
+	ofs, otmp, err := makeOverlayFS(dfs, "test_kustomizations/basic/initial")
+	defer os.RemoveAll(otmp)
+	assert.Equal(t, nil, err, nil)
+
+	// real kustomization files next to the virtual one are hidden
+	assert.Equal(t, true, dfs.Exists("test_kustomizations/basic/initial/kustomization.yaml"), nil)
+	assert.Equal(t, false, ofs.Exists("test_kustomizations/basic/initial/kustomization.yaml"), nil)
+
+	// other files are not
+	assert.Equal(t, true, ofs.Exists("test_kustomizations/basic/initial/namespace.yaml"), nil)
+	assert.Equal(t, true, ofs.Exists("test_kustomizations/basic/modified/kustomization.yaml"), nil)
+
+	// the virtual Kustomization lives in dir
+	err = ofs.WriteFile("test_kustomizations/basic/initial/Kustomization", []byte("test"))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, false, dfs.Exists("test_kustomizations/basic/initial/Kustomization"), nil)
+	assert.Equal(t, true, ofs.Exists("test_kustomizations/basic/initial/Kustomization"), nil)
+
+	err = ofs.RemoveAll("test_kustomizations/basic/initial/Kustomization")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, false, dfs.Exists(filepath.Join(otmp, KFILENAME)), nil)
+}
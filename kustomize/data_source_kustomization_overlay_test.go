@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
 	"sigs.k8s.io/kustomize/api/konfig"
 	"sigs.k8s.io/kustomize/kyaml/filesys"
@@ -1031,21 +1032,53 @@ output "check" {
 func TestDataSourceKustomizationOverlay_conflict(t *testing.T) {
 	fSys := filesys.MakeFsOnDisk()
 	for _, n := range konfig.RecognizedKustomizationFileNames() {
-		fSys.WriteFile(n, []byte{})
+		fSys.WriteFile(n, []byte("invalid"))
 
-		err := refuseExistingKustomization(fSys)
-		assert.EqualErrorf(
-			t,
-			err,
-			fmt.Sprintf("buildKustomizeOverlay: Can not build dynamic overlay, found %q in working directory.", n),
-			"",
-			nil,
-		)
+		d := schema.TestResourceDataRaw(t, dataSourceKustomizationOverlay().Schema, map[string]interface{}{
+			"resources": []interface{}{"test_kustomizations/basic/initial"},
+		})
+		err := kustomizationOverlay(d, &Config{})
+		assert.Equal(t, nil, err, nil)
+		assert.Equal(t, 4, d.Get("ids").(*schema.Set).Len(), nil)
+
+		// existing files are left untouched
+		c, rerr := fSys.ReadFile(n)
+		assert.Equal(t, []byte("invalid"), c, nil)
+		assert.Equal(t, nil, rerr, nil)
 
 		fSys.RemoveAll(n)
 	}
 }
 
+func TestDataSourceKustomizationOverlay_reservedFiles(t *testing.T) {
+	for name, msg := range map[string]string{
+		"kustomization.yaml":         `"kustomization.yaml" is reserved for the generated Kustomization`,
+		"./Kustomization":            `"./Kustomization" is reserved for the generated Kustomization`,
+		"_resource_manifests/0.yaml": `"_resource_manifests/0.yaml" is reserved for resource_manifests`,
+	} {
+		d := schema.TestResourceDataRaw(t, dataSourceKustomizationOverlay().Schema, map[string]interface{}{
+			"resources": []interface{}{"test_kustomizations/basic/initial"},
+			"files": map[string]interface{}{
+				name: "resources: []\n",
+			},
+		})
+
+		err := kustomizationOverlay(d, &Config{})
+		assert.Equal(t, fmt.Sprintf("buildKustomizeOverlay: files: %s", msg), err.Error(), nil)
+	}
+
+	// files in subdirectories are not reserved
+	d := schema.TestResourceDataRaw(t, dataSourceKustomizationOverlay().Schema, map[string]interface{}{
+		"resources": []interface{}{"test_kustomizations/basic/initial"},
+		"files": map[string]interface{}{
+			"test/kustomization.yaml": "resources: []\n",
+		},
+	})
+
+	err := kustomizationOverlay(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+}
+
 // Test patch options attr
 func TestDataSourceKustomizationOverlay_patchOptionsNoop(t *testing.T) {
 
@@ -1793,3 +1826,258 @@ func testDataSourceKustomizationOverlayConfig_helm_charts_capabilities_set() str
 		value = data.kustomization_overlay.test.manifests["_/ConfigMap/_/apiversions-configmap"]
 	}`
 }
+
+// Test set attr
+func TestDataSourceKustomizationOverlay_set(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceKustomizationOverlayConfig_set(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_overlay.test", "set.#", "3"),
+					resource.TestCheckOutput("check", "{\"apiVersion\":\"apps/v1\",\"kind\":\"Deployment\",\"metadata\":{\"annotations\":{\"example.com/replicas\":\"3\"},\"creationTimestamp\":null,\"labels\":{\"app\":\"test\"},\"name\":\"test\",\"namespace\":\"test-basic\"},\"spec\":{\"replicas\":3,\"selector\":{\"matchLabels\":{\"app\":\"test\"}},\"strategy\":{},\"template\":{\"metadata\":{\"creationTimestamp\":null,\"labels\":{\"app\":\"test\"}},\"spec\":{\"containers\":[{\"image\":\"nginx:1.25\",\"name\":\"nginx\",\"resources\":{}}]}}},\"status\":{}}"),
+				),
+			},
+			{
+				Config:      testDataSourceKustomizationOverlayConfig_setMissingField(),
+				ExpectError: regexp.MustCompile("set \"spec.paused\" on \"apps/Deployment/test-basic/test\": field does not exist, set create = true to add it"),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationOverlayConfig_set() string {
+	return `
+data "kustomization_overlay" "test" {
+	resources = [
+		"test_kustomizations/basic/initial",
+	]
+
+	set {
+		target {
+			kind = "Deployment"
+			name = "test"
+		}
+		field_path = "spec.replicas"
+		value = "3"
+	}
+
+	set {
+		target {
+			kind = "Deployment"
+		}
+		field_path = "spec.template.spec.containers.[name=nginx].image"
+		value = "nginx:1.25"
+	}
+
+	set {
+		target {
+			kind = "Deployment"
+		}
+		field_path = "metadata.annotations.example\\.com/replicas"
+		value = "3"
+		create = true
+	}
+}
+
+output "check" {
+	value = data.kustomization_overlay.test.manifests["apps/Deployment/test-basic/test"]
+}
+`
+}
+
+func testDataSourceKustomizationOverlayConfig_setMissingField() string {
+	return `
+data "kustomization_overlay" "test" {
+	resources = [
+		"test_kustomizations/basic/initial",
+	]
+
+	set {
+		target {
+			kind = "Deployment"
+		}
+		field_path = "spec.paused"
+		value = "true"
+	}
+}
+`
+}
+
+// Test resource_manifests and files attrs
+func TestDataSourceKustomizationOverlay_inlineFiles(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceKustomizationOverlayConfig_inlineFiles(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_overlay.test", "resource_manifests.#", "1"),
+					resource.TestCheckResourceAttr("data.kustomization_overlay.test", "files.%", "3"),
+					resource.TestCheckResourceAttr("data.kustomization_overlay.test", "ids.#", "4"),
+					resource.TestCheckOutput("ns", "{\"apiVersion\":\"v1\",\"kind\":\"Namespace\",\"metadata\":{\"name\":\"test-overlay-inline\"}}"),
+					resource.TestCheckOutput("cm_patched", "{\"apiVersion\":\"v1\",\"data\":{\"patched\":\"true\"},\"kind\":\"ConfigMap\",\"metadata\":{\"name\":\"test1\",\"namespace\":\"test-overlay-inline\"}}"),
+					resource.TestCheckOutput("cm_generated", "{\"apiVersion\":\"v1\",\"data\":{\"app.properties\":\"KEY=VALUE\"},\"kind\":\"ConfigMap\",\"metadata\":{\"name\":\"test-generated-6664m4mcf9\",\"namespace\":\"test-overlay-inline\"}}"),
+				),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationOverlayConfig_inlineFiles() string {
+	return `
+data "kustomization_overlay" "test" {
+	resources = [
+		"namespace.yaml",
+	]
+
+	resource_manifests = [
+		<<-EOT
+		apiVersion: v1
+		kind: ConfigMap
+		metadata:
+		  name: test1
+		  namespace: test-overlay-inline
+		---
+		apiVersion: v1
+		kind: ConfigMap
+		metadata:
+		  name: test2
+		  namespace: test-overlay-inline
+		EOT
+	]
+
+	files = {
+		"namespace.yaml" = <<-EOT
+		apiVersion: v1
+		kind: Namespace
+		metadata:
+		  name: test-overlay-inline
+		EOT
+		"patch.yaml" = <<-EOT
+		apiVersion: v1
+		kind: ConfigMap
+		metadata:
+		  name: test1
+		  namespace: test-overlay-inline
+		data:
+		  patched: "true"
+		EOT
+		"config/app.properties" = "KEY=VALUE"
+	}
+
+	patches {
+		path = "patch.yaml"
+	}
+
+	config_map_generator {
+		name = "test-generated"
+		namespace = "test-overlay-inline"
+		files = [
+			"config/app.properties",
+		]
+	}
+}
+
+output "ns" {
+	value = data.kustomization_overlay.test.manifests["_/Namespace/_/test-overlay-inline"]
+}
+
+output "cm_patched" {
+	value = data.kustomization_overlay.test.manifests["_/ConfigMap/test-overlay-inline/test1"]
+}
+
+output "cm_generated" {
+	value = data.kustomization_overlay.test.manifests["_/ConfigMap/test-overlay-inline/test-generated-6664m4mcf9"]
+}
+`
+}
+
+// Test base_dir attr
+func TestDataSourceKustomizationOverlay_baseDir(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceKustomizationOverlayConfig_baseDir(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_overlay.test", "ids.#", "4"),
+					resource.TestCheckOutput("ns", "{\"apiVersion\":\"v1\",\"kind\":\"Namespace\",\"metadata\":{\"name\":\"test-base-dir\"}}"),
+				),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationOverlayConfig_baseDir() string {
+	return `
+data "kustomization_overlay" "test" {
+	base_dir = "${path.module}/test_kustomizations/basic/initial"
+
+	namespace = "test-base-dir"
+
+	resources = [
+		"namespace.yaml",
+		"../../_example_app",
+	]
+
+	patches {
+		target {
+			kind = "Namespace"
+		}
+		patch = <<-EOF
+			- op: replace
+			  path: /metadata/name
+			  value: test-base-dir
+		EOF
+	}
+}
+
+output "ns" {
+	value = data.kustomization_overlay.test.manifests["_/Namespace/_/test-base-dir"]
+}
+`
+}
+
+// Test track_origins attr
+func TestDataSourceKustomizationOverlay_trackOrigins(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceKustomizationOverlayConfig_trackOrigins(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_overlay.test", "origins.%", "4"),
+					resource.TestCheckResourceAttr("data.kustomization_overlay.test", "origins._/Namespace/_/test-basic", "test_kustomizations/basic/initial/namespace.yaml"),
+					resource.TestCheckResourceAttr("data.kustomization_overlay.test", "origins.apps/Deployment/test-basic/test", "test_kustomizations/_example_app/deployment.yaml"),
+					resource.TestCheckOutput("ns", "{\"apiVersion\":\"v1\",\"kind\":\"Namespace\",\"metadata\":{\"name\":\"test-basic\"}}"),
+				),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationOverlayConfig_trackOrigins() string {
+	return `
+data "kustomization_overlay" "test" {
+	track_origins = true
+
+	resources = [
+		"test_kustomizations/basic/initial",
+	]
+}
+
+output "ns" {
+	value = data.kustomization_overlay.test.manifests["_/Namespace/_/test-basic"]
+}
+`
+}
@@ -0,0 +1,258 @@
+package kustomize
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+func dataSourceKustomizationPreflight() *schema.Resource {
+	return &schema.Resource{
+		Read: kustomizationPreflight,
+
+		Schema: map[string]*schema.Schema{
+			"manifests": &schema.Schema{
+				Type:     schema.TypeMap,
+				Required: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"fail_on_problems": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"problems": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+// A kind as served by the cluster or defined by a CRD in the manifests.
+type servedKind struct {
+	groupVersions []string
+	namespaced    bool
+}
+
+func (sk *servedKind) serves(groupVersion string) bool {
+	for _, gv := range sk.groupVersions {
+		if gv == groupVersion {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checks all manifests against the cluster's API discovery in one pass,
+// instead of failing on the first manifest during the resource diff.
+func kustomizationPreflight(d *schema.ResourceData, m interface{}) error {
+	dc := m.(*Config).Discovery
+
+	_, lists, err := dc.ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return fmt.Errorf("kustomizationPreflight: %s", err)
+	}
+
+	served, err := getServedKinds(lists)
+	if err != nil {
+		return fmt.Errorf("kustomizationPreflight: %s", err)
+	}
+
+	manifests := d.Get("manifests").(map[string]interface{})
+
+	objs := make(map[string]*k8sunstructured.Unstructured)
+	for id, v := range manifests {
+		body, err := manifestToJSON([]byte(v.(string)))
+		if err != nil {
+			return fmt.Errorf("kustomizationPreflight: %q: %s", id, err)
+		}
+
+		u := &k8sunstructured.Unstructured{}
+		err = u.UnmarshalJSON(body)
+		if err != nil {
+			return fmt.Errorf("kustomizationPreflight: %q: %s", id, err)
+		}
+
+		objs[id] = u
+	}
+
+	problems := getPreflightProblems(objs, served)
+
+	p := make(map[string]string)
+	for id, msgs := range problems {
+		j, err := json.Marshal(msgs)
+		if err != nil {
+			return fmt.Errorf("kustomizationPreflight: %q: %s", id, err)
+		}
+		p[id] = string(j)
+	}
+	d.Set("problems", p)
+
+	if len(problems) > 0 && d.Get("fail_on_problems").(bool) {
+		return fmt.Errorf("kustomizationPreflight: %s", fmtProblems(problems))
+	}
+
+	d.SetId(getManifestsID(manifests))
+
+	return nil
+}
+
+func getServedKinds(lists []*k8smetav1.APIResourceList) (map[k8sschema.GroupKind]*servedKind, error) {
+	served := make(map[k8sschema.GroupKind]*servedKind)
+	for _, l := range lists {
+		gv, err := k8sschema.ParseGroupVersion(l.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range l.APIResources {
+			// skip subresources, e.g. deployments/scale
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+
+			gk := k8sschema.GroupKind{Group: gv.Group, Kind: r.Kind}
+			if served[gk] == nil {
+				served[gk] = &servedKind{namespaced: r.Namespaced}
+			}
+			served[gk].groupVersions = append(served[gk].groupVersions, l.GroupVersion)
+		}
+	}
+
+	return served, nil
+}
+
+// CRDs in the manifests take precedence over the cluster,
+// because they are what the cluster will serve after the apply.
+func getManifestsCRDKinds(objs map[string]*k8sunstructured.Unstructured) map[k8sschema.GroupKind]*servedKind {
+	crds := make(map[k8sschema.GroupKind]*servedKind)
+	for _, u := range objs {
+		gvk := u.GroupVersionKind()
+		if gvk.Group != "apiextensions.k8s.io" || gvk.Kind != "CustomResourceDefinition" {
+			continue
+		}
+
+		spec, _ := u.Object["spec"].(map[string]interface{})
+		names, _ := spec["names"].(map[string]interface{})
+		group, _ := spec["group"].(string)
+		kind, _ := names["kind"].(string)
+		scope, _ := spec["scope"].(string)
+		versions, _ := spec["versions"].([]interface{})
+
+		sk := &servedKind{namespaced: scope != "Cluster"}
+		for _, v := range versions {
+			vm, _ := v.(map[string]interface{})
+			if served, ok := vm["served"].(bool); ok && !served {
+				continue
+			}
+			sk.groupVersions = append(sk.groupVersions, fmt.Sprintf("%s/%s", group, vm["name"]))
+		}
+
+		crds[k8sschema.GroupKind{Group: group, Kind: kind}] = sk
+	}
+
+	return crds
+}
+
+func getPreflightProblems(objs map[string]*k8sunstructured.Unstructured, served map[k8sschema.GroupKind]*servedKind) map[string][]string {
+	crds := getManifestsCRDKinds(objs)
+
+	problems := make(map[string][]string)
+	for id, u := range objs {
+		gvk := u.GroupVersionKind()
+		gk := gvk.GroupKind()
+
+		sk, ok := crds[gk]
+		if !ok {
+			sk, ok = served[gk]
+		}
+
+		if !ok {
+			problems[id] = append(problems[id], getUnservedKindProblem(gvk, served))
+			continue
+		}
+
+		if len(sk.groupVersions) == 0 {
+			problems[id] = append(problems[id], fmt.Sprintf("kind %s/%s is defined but serves no versions", gvk.Group, gvk.Kind))
+		} else if !sk.serves(u.GetAPIVersion()) {
+			problems[id] = append(problems[id], fmt.Sprintf("apiVersion %q is not served, served versions are: %s", u.GetAPIVersion(), strings.Join(sk.groupVersions, ", ")))
+		}
+
+		if sk.namespaced && u.GetNamespace() == "" {
+			problems[id] = append(problems[id], "is namespace scoped and must set metadata.namespace")
+		}
+		if !sk.namespaced && u.GetNamespace() != "" {
+			problems[id] = append(problems[id], "is not namespace scoped but has metadata.namespace set")
+		}
+	}
+
+	return problems
+}
+
+func getUnservedKindProblem(gvk k8sschema.GroupVersionKind, served map[k8sschema.GroupKind]*servedKind) string {
+	// the API version was removed, e.g. extensions/v1beta1 Ingress
+	var alternatives []string
+	for gk, sk := range served {
+		if gk.Kind == gvk.Kind {
+			alternatives = append(alternatives, sk.groupVersions[0])
+		}
+	}
+	if len(alternatives) > 0 {
+		sort.Strings(alternatives)
+		return fmt.Sprintf("apiVersion %q is not served, kind %s is served as: %s", gvk.GroupVersion().String(), gvk.Kind, strings.Join(alternatives, ", "))
+	}
+
+	// CRD groups must contain a dot, built-in groups don't
+	if strings.Contains(gvk.Group, ".") {
+		return fmt.Sprintf("kind %s/%s is not served and its CustomResourceDefinition is neither in the manifests nor the cluster", gvk.Group, gvk.Kind)
+	}
+
+	return fmt.Sprintf("kind %q is unknown to the cluster", gvk.Kind)
+}
+
+func fmtProblems(problems map[string][]string) string {
+	ids := make([]string, 0, len(problems))
+	for id := range problems {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var lines []string
+	for _, id := range ids {
+		for _, msg := range problems[id] {
+			lines = append(lines, fmt.Sprintf("%q: %s", id, msg))
+		}
+	}
+
+	return fmt.Sprintf("%d problem(s) found:\n%s", len(lines), strings.Join(lines, "\n"))
+}
+
+func getManifestsID(manifests map[string]interface{}) string {
+	ids := make([]string, 0, len(manifests))
+	for id := range manifests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	h := sha512.New()
+	for _, id := range ids {
+		fmt.Fprintf(h, "%d:%s\n", len(id), id)
+		v := manifests[id].(string)
+		fmt.Fprintf(h, "%d:%s\n", len(v), v)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
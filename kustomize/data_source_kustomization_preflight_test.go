@@ -0,0 +1,158 @@
+package kustomize
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestAccDataSourceKustomizationPreflight_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		//PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceKustomizationPreflightConfig_basic("test_kustomizations/basic/initial"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_preflight.test", "problems.%", "0"),
+				),
+			},
+			{
+				Config:      testAccDataSourceKustomizationPreflightConfig_problems(),
+				ExpectError: regexp.MustCompile(`kind "NotAKind" is unknown to the cluster`),
+			},
+		},
+	})
+}
+
+func testAccDataSourceKustomizationPreflightConfig_basic(path string) string {
+	return testAccDataSourceKustomizationConfig_basic(path) + `
+data "kustomization_preflight" "test" {
+	manifests = data.kustomization_build.test.manifests
+}
+`
+}
+
+func testAccDataSourceKustomizationPreflightConfig_problems() string {
+	return `
+data "kustomization_preflight" "test" {
+	manifests = {
+		"_/NotAKind/_/test" = jsonencode({
+			apiVersion = "v1"
+			kind       = "NotAKind"
+			metadata = {
+				name = "test"
+			}
+		})
+	}
+}
+`
+}
+
+func TestKustomizationPreflight(t *testing.T) {
+	dc := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	dc.Resources = []*k8smetav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []k8smetav1.APIResource{
+				{Name: "namespaces", Kind: "Namespace", Namespaced: false},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []k8smetav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true},
+				{Name: "deployments/scale", Kind: "Scale", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "networking.k8s.io/v1",
+			APIResources: []k8smetav1.APIResource{
+				{Name: "ingresses", Kind: "Ingress", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "apiextensions.k8s.io/v1",
+			APIResources: []k8smetav1.APIResource{
+				{Name: "customresourcedefinitions", Kind: "CustomResourceDefinition", Namespaced: false},
+			},
+		},
+	}
+
+	manifests := map[string]interface{}{
+		"_/Namespace/_/test":                    `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"test"}}`,
+		"_/ConfigMap/test/test":                 `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test","namespace":"test"}}`,
+		"_/ConfigMap/_/no-namespace":            `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"no-namespace"}}`,
+		"_/Namespace/test/namespaced":           `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"namespaced","namespace":"test"}}`,
+		"apps/Deployment/test/test":             `{"apiVersion":"apps/v1beta1","kind":"Deployment","metadata":{"name":"test","namespace":"test"}}`,
+		"extensions/Ingress/test/test":          `{"apiVersion":"extensions/v1beta1","kind":"Ingress","metadata":{"name":"test","namespace":"test"}}`,
+		"_/NotAKind/test/test":                  `{"apiVersion":"v1","kind":"NotAKind","metadata":{"name":"test","namespace":"test"}}`,
+		"cert-manager.io/Certificate/test/test": `{"apiVersion":"cert-manager.io/v1","kind":"Certificate","metadata":{"name":"test","namespace":"test"}}`,
+		"apiextensions.k8s.io/CustomResourceDefinition/_/clusteredcrds.test.example.com": `{"apiVersion":"apiextensions.k8s.io/v1","kind":"CustomResourceDefinition","metadata":{"name":"clusteredcrds.test.example.com"},"spec":{"group":"test.example.com","names":{"kind":"Clusteredcrd"},"scope":"Cluster","versions":[{"name":"v1alpha1","served":true},{"name":"v1alpha0","served":false}]}}`,
+		"test.example.com/Clusteredcrd/_/test":                                           `{"apiVersion":"test.example.com/v1alpha1","kind":"Clusteredcrd","metadata":{"name":"test"}}`,
+		"test.example.com/Clusteredcrd/test/old":                                         `{"apiVersion":"test.example.com/v1alpha0","kind":"Clusteredcrd","metadata":{"name":"old","namespace":"test"}}`,
+		"apiextensions.k8s.io/CustomResourceDefinition/_/retiredcrds.test.example.com":   `{"apiVersion":"apiextensions.k8s.io/v1","kind":"CustomResourceDefinition","metadata":{"name":"retiredcrds.test.example.com"},"spec":{"group":"test.example.com","names":{"kind":"Retiredcrd"},"scope":"Namespaced","versions":[{"name":"v1","served":false}]}}`,
+		"test.example.com/Retiredcrd/test/test":                                          `{"apiVersion":"test.example.com/v1","kind":"Retiredcrd","metadata":{"name":"test","namespace":"test"}}`,
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceKustomizationPreflight().Schema, map[string]interface{}{
+		"manifests":        manifests,
+		"fail_on_problems": false,
+	})
+
+	err := kustomizationPreflight(d, &Config{Discovery: dc})
+	assert.Equal(t, nil, err, nil)
+
+	problems := make(map[string][]string)
+	for id, j := range d.Get("problems").(map[string]interface{}) {
+		var msgs []string
+		err := json.Unmarshal([]byte(j.(string)), &msgs)
+		assert.Equal(t, nil, err, nil)
+		problems[id] = msgs
+	}
+
+	assert.Equal(t, map[string][]string{
+		"_/ConfigMap/_/no-namespace": []string{
+			"is namespace scoped and must set metadata.namespace",
+		},
+		"_/Namespace/test/namespaced": []string{
+			"is not namespace scoped but has metadata.namespace set",
+		},
+		"apps/Deployment/test/test": []string{
+			`apiVersion "apps/v1beta1" is not served, served versions are: apps/v1`,
+		},
+		"extensions/Ingress/test/test": []string{
+			`apiVersion "extensions/v1beta1" is not served, kind Ingress is served as: networking.k8s.io/v1`,
+		},
+		"_/NotAKind/test/test": []string{
+			`kind "NotAKind" is unknown to the cluster`,
+		},
+		"cert-manager.io/Certificate/test/test": []string{
+			"kind cert-manager.io/Certificate is not served and its CustomResourceDefinition is neither in the manifests nor the cluster",
+		},
+		"test.example.com/Clusteredcrd/test/old": []string{
+			`apiVersion "test.example.com/v1alpha0" is not served, served versions are: test.example.com/v1alpha1`,
+			"is not namespace scoped but has metadata.namespace set",
+		},
+		"test.example.com/Retiredcrd/test/test": []string{
+			"kind test.example.com/Retiredcrd is defined but serves no versions",
+		},
+	}, problems, nil)
+
+	d = schema.TestResourceDataRaw(t, dataSourceKustomizationPreflight().Schema, map[string]interface{}{
+		"manifests": manifests,
+	})
+
+	err = kustomizationPreflight(d, &Config{Discovery: dc})
+	assert.Regexp(t, `^kustomizationPreflight: 9 problem\(s\) found:\n"_/ConfigMap/_/no-namespace": is namespace scoped`, err.Error(), nil)
+}
@@ -1,10 +1,18 @@
 package kustomize
 
 import (
+	"fmt"
 	"math"
+	"path/filepath"
+	"sync"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
 func TestDeterminePrefix(t *testing.T) {
@@ -30,6 +38,49 @@ func TestDeterminePrefix(t *testing.T) {
 	}
 }
 
+func TestPriorityRulesTier(t *testing.T) {
+	tier := getPriorityRulesSchema().Elem.(*schema.Resource).Schema["tier"]
+
+	_, errs := tier.ValidateFunc(100, "tier")
+	assert.Equal(t, 0, len(errs), nil)
+
+	_, errs = tier.ValidateFunc(-1, "tier")
+	assert.Equal(t, 1, len(errs), nil)
+}
+
+func TestGetDefaultPriority(t *testing.T) {
+	for _, id := range residListFirst {
+		kr := mustParseProviderId(id)
+		assert.Equal(t, 0, getDefaultPriority(priorityDefaultsLegacy, kr), nil)
+	}
+
+	for _, id := range residListLast {
+		kr := mustParseProviderId(id)
+		assert.Equal(t, 2, getDefaultPriority(priorityDefaultsLegacy, kr), nil)
+	}
+
+	assert.Equal(t, 3, getDefaultPriorityTiers(priorityDefaultsLegacy), nil)
+	assert.Equal(t, 5, getDefaultPriorityTiers(priorityDefaultsHelm), nil)
+
+	helm := map[string]int{
+		"scheduling.k8s.io/PriorityClass/_/test":                             0,
+		"_/Namespace/_/test":                                                 0,
+		"apiextensions.k8s.io/CustomResourceDefinition/_/test":               0,
+		"_/ServiceAccount/test-ns/test":                                      1,
+		"rbac.authorization.k8s.io/ClusterRoleBinding/_/test":                1,
+		"_/ConfigMap/test-ns/test":                                           1,
+		"apps/Deployment/test-ns/test":                                       2,
+		"_/Service/test-ns/test":                                             2,
+		"example.com/Custom/test-ns/test":                                    3,
+		"apiregistration.k8s.io/APIService/_/test":                           4,
+		"admissionregistration.k8s.io/ValidatingWebhookConfiguration/_/test": 4,
+	}
+	for id, e := range helm {
+		kr := mustParseProviderId(id)
+		assert.Equal(t, e, getDefaultPriority(priorityDefaultsHelm, kr), id)
+	}
+}
+
 func TestPrefixHash(t *testing.T) {
 	ti := uint32(math.MaxInt32 / 1000)
 	i := prefixHash(uint32(1), ti)
@@ -183,3 +234,314 @@ var residListLast = []string{
 	"admissionregistration.k8s.io/ValidatingWebhookConfiguration/_/test",
 	"admissionregistration.k8s.io/ValidatingWebhookConfiguration/test-ns/test",
 }
+
+func TestApplySetValues(t *testing.T) {
+	fSys := filesys.MakeFsOnDisk()
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+
+	rm, err := k.Run(fSys, "test_kustomizations/basic/initial")
+	assert.Equal(t, nil, err, nil)
+
+	d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+		"path": "test_kustomizations/basic/initial",
+		"set": []interface{}{
+			map[string]interface{}{
+				"target": []interface{}{
+					map[string]interface{}{
+						"kind": "Deployment",
+						"name": "test",
+					},
+				},
+				"field_path": "spec.template.spec.containers.[name=nginx].image",
+				"value":      "nginx:1.25",
+			},
+			map[string]interface{}{
+				"target": []interface{}{
+					map[string]interface{}{
+						"label_selector": "app=test",
+					},
+				},
+				"field_path": "metadata.annotations.example\\.com/owner",
+				"value":      "terraform",
+				"create":     true,
+			},
+		},
+	})
+
+	err = applySetValues(d, rm)
+	assert.Equal(t, nil, err, nil)
+
+	res, err := flattenKustomizationResources(rm)
+	assert.Equal(t, nil, err, nil)
+	assert.Contains(t, res["apps/Deployment/test-basic/test"], `"image":"nginx:1.25"`, nil)
+	assert.Contains(t, res["apps/Deployment/test-basic/test"], `"example.com/owner":"terraform"`, nil)
+	assert.Contains(t, res["_/Service/test-basic/test"], `"example.com/owner":"terraform"`, nil)
+	assert.NotContains(t, res["_/Namespace/_/test-basic"], `"example.com/owner"`, nil)
+}
+
+func TestApplySetValuesErrors(t *testing.T) {
+	fSys := filesys.MakeFsOnDisk()
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+
+	for _, s := range []map[string]interface{}{
+		// target field does not exist and create is not set
+		{
+			"target":     []interface{}{map[string]interface{}{"kind": "Deployment"}},
+			"field_path": "spec.paused",
+			"value":      "true",
+		},
+		// target does not match any resources
+		{
+			"target":     []interface{}{map[string]interface{}{"kind": "StatefulSet"}},
+			"field_path": "spec.replicas",
+			"value":      "3",
+		},
+		// target field is not a scalar
+		{
+			"target":     []interface{}{map[string]interface{}{"kind": "Deployment"}},
+			"field_path": "spec.template",
+			"value":      "test",
+		},
+	} {
+		rm, err := k.Run(fSys, "test_kustomizations/basic/initial")
+		assert.Equal(t, nil, err, nil)
+
+		d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+			"path": "test_kustomizations/basic/initial",
+			"set":  []interface{}{s},
+		})
+
+		err = applySetValues(d, rm)
+		assert.NotEqual(t, nil, err, nil)
+	}
+}
+
+func TestApplyFilters(t *testing.T) {
+	fSys := filesys.MakeFsOnDisk()
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+
+	for _, tc := range []struct {
+		include []interface{}
+		exclude []interface{}
+		ids     []string
+	}{
+		{
+			include: []interface{}{map[string]interface{}{"kind": "Namespace"}},
+			ids:     []string{"_/Namespace/_/test-basic"},
+		},
+		{
+			exclude: []interface{}{map[string]interface{}{"kind": "Service|Ingress"}},
+			ids:     []string{"_/Namespace/_/test-basic", "apps/Deployment/test-basic/test"},
+		},
+		{
+			include: []interface{}{
+				map[string]interface{}{"label_selector": "app=test"},
+				map[string]interface{}{"group": "networking.k8s.io"},
+			},
+			exclude: []interface{}{map[string]interface{}{"name": "te.*", "kind": "Service"}},
+			ids:     []string{"apps/Deployment/test-basic/test", "networking.k8s.io/Ingress/test-basic/test"},
+		},
+	} {
+		rm, err := k.Run(fSys, "test_kustomizations/basic/initial")
+		assert.Equal(t, nil, err, nil)
+
+		d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+			"path":    "test_kustomizations/basic/initial",
+			"include": tc.include,
+			"exclude": tc.exclude,
+		})
+
+		err = applyFilters(d, rm)
+		assert.Equal(t, nil, err, nil)
+
+		ids, _, err := flattenKustomizationIDs(rm, priorityDefaultsLegacy, nil)
+		assert.Equal(t, nil, err, nil)
+		assert.ElementsMatch(t, tc.ids, ids, nil)
+	}
+
+	rm, err := k.Run(fSys, "test_kustomizations/basic/initial")
+	assert.Equal(t, nil, err, nil)
+
+	d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+		"path":    "test_kustomizations/basic/initial",
+		"exclude": []interface{}{map[string]interface{}{"label_selector": "app in (("}},
+	})
+
+	err = applyFilters(d, rm)
+	assert.NotEqual(t, nil, err, nil)
+}
+
+// Builds many overlays and kustomizations in parallel,
+// run with -race to detect shared state between builds.
+func TestRunKustomizeBuildParallel(t *testing.T) {
+	n := 32
+	ds := make([]*schema.ResourceData, n)
+	for i := 0; i < n; i++ {
+		switch i % 4 {
+		case 0:
+			ds[i] = schema.TestResourceDataRaw(t, dataSourceKustomizationOverlay().Schema, map[string]interface{}{
+				"namespace": fmt.Sprintf("test-parallel-%d", i),
+				"resources": []interface{}{"test_kustomizations/basic/initial"},
+			})
+		case 1:
+			ds[i] = schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+				"path": "test_kustomizations/basic/initial",
+			})
+		case 2:
+			// sets the global OpenAPI schema
+			ds[i] = schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+				"files": map[string]interface{}{
+					"kustomization.yaml": "openapi:\n  version: v1.21.2\nresources:\n- ns.yaml\n",
+					"ns.yaml":            "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: test-parallel\n",
+				},
+			})
+		case 3:
+			// a nested base sets the global OpenAPI schema
+			ds[i] = schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+				"files": map[string]interface{}{
+					"kustomization.yaml":      "resources:\n- base\n",
+					"base/kustomization.yaml": "openapi:\n  path: schema.json\nresources:\n- ns.yaml\n",
+					"base/schema.json":        `{"swagger": "2.0", "info": {"title": "test", "version": "v1"}, "paths": {}, "definitions": {}}`,
+					"base/ns.yaml":            "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: test-parallel-base\n",
+				},
+			})
+		}
+	}
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%4 == 0 {
+				errs[i] = kustomizationOverlay(ds[i], &Config{})
+			} else {
+				errs[i] = kustomizationBuild(ds[i], &Config{})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		assert.Equal(t, nil, errs[i], nil)
+
+		switch i % 4 {
+		case 0:
+			assert.Equal(t, 4, ds[i].Get("ids").(*schema.Set).Len(), nil)
+			assert.Contains(t, ds[i].Get("ids").(*schema.Set).List(), fmt.Sprintf("apps/Deployment/test-parallel-%d/test", i), nil)
+		case 1:
+			assert.Contains(t, ds[i].Get("ids").(*schema.Set).List(), "apps/Deployment/test-basic/test", nil)
+		case 2:
+			assert.Equal(t, []interface{}{"_/Namespace/_/test-parallel"}, ds[i].Get("ids").(*schema.Set).List(), nil)
+		case 3:
+			assert.Equal(t, []interface{}{"_/Namespace/_/test-parallel-base"}, ds[i].Get("ids").(*schema.Set).List(), nil)
+		}
+	}
+}
+
+func TestMakeBuildMetadataFSRemote(t *testing.T) {
+	remote := "https://github.com/kbst/terraform-provider-kustomization//kustomize/test_kustomizations/basic/initial?ref=main"
+
+	fSys, root, err := makeBuildMetadataFS(filesys.MakeFsInMemory(), remote, []string{"originAnnotations"})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, true, fSys.IsDir(root), nil)
+
+	data, err := fSys.ReadFile(filepath.Join(root, "kustomization.yaml"))
+	assert.Equal(t, nil, err, nil)
+
+	k := types.Kustomization{}
+	err = yaml.Unmarshal(data, &k)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string{remote}, k.Resources, nil)
+	assert.Equal(t, []string{"originAnnotations"}, k.BuildMetadata, nil)
+}
+
+func TestSetsOpenAPISchema(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	fSys.WriteFile("default/kustomization.yaml", []byte("resources:\n- ns.yaml\n- ../base\n"))
+	fSys.WriteFile("default/ns.yaml", []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: test\n"))
+	fSys.WriteFile("base/kustomization.yaml", []byte("resources:\n- ../default\n"))
+	fSys.WriteFile("root/kustomization.yaml", []byte("openapi:\n  version: v1.21.2\n"))
+	fSys.WriteFile("nested/kustomization.yaml", []byte("resources:\n- ../default\ncomponents:\n- ../root\n"))
+	fSys.WriteFile("remote/kustomization.yaml", []byte("resources:\n- github.com/kbst/terraform-kubestack//test\n"))
+
+	assert.Equal(t, false, setsOpenAPISchema(fSys, "default"), nil)
+	assert.Equal(t, true, setsOpenAPISchema(fSys, "root"), nil)
+	assert.Equal(t, true, setsOpenAPISchema(fSys, "nested"), nil)
+	assert.Equal(t, true, setsOpenAPISchema(fSys, "remote"), nil)
+	assert.Equal(t, true, setsOpenAPISchema(fSys, "missing"), nil)
+}
+
+func TestGetKustomizeOptions(t *testing.T) {
+	opts := getKustomizeOptions(map[string]interface{}{
+		"load_restrictor":     "none",
+		"enable_helm":         true,
+		"helm_path":           "/usr/local/bin/helm",
+		"helm_api_versions":   []interface{}{"monitoring.coreos.com/v1"},
+		"helm_kube_version":   "1.29.0",
+		"helm_debug":          true,
+		"add_managedby_label": true,
+		"reorder":             "legacy",
+	})
+
+	assert.Equal(t, types.LoadRestrictionsNone, opts.LoadRestrictions, nil)
+	assert.Equal(t, krusty.ReorderOptionLegacy, opts.Reorder, nil)
+	assert.Equal(t, true, opts.AddManagedbyLabel, nil)
+	assert.Equal(t, types.HelmConfig{
+		Enabled:     true,
+		Command:     "/usr/local/bin/helm",
+		ApiVersions: []string{"monitoring.coreos.com/v1"},
+		KubeVersion: "1.29.0",
+		Debug:       true,
+	}, opts.PluginConfig.HelmConfig, nil)
+
+	assert.Equal(t, krusty.MakeDefaultOptions(), getKustomizeOptions(nil), nil)
+}
+
+func TestGetMergedKustomizeOptions(t *testing.T) {
+	defaults := map[string]interface{}{
+		"enable_helm": true,
+		"helm_path":   "/usr/local/bin/helm",
+		"reorder":     "legacy",
+	}
+
+	d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+		"path": "test_kustomizations/basic/initial",
+		"kustomize_options": []interface{}{
+			map[string]interface{}{
+				"reorder":        "none",
+				"build_metadata": []interface{}{"originAnnotations"},
+			},
+		},
+	})
+
+	kOpts := getMergedKustomizeOptions(d, defaults)
+	assert.Equal(t, true, kOpts["enable_helm"], nil)
+	assert.Equal(t, "/usr/local/bin/helm", kOpts["helm_path"], nil)
+	assert.Equal(t, "none", kOpts["reorder"], nil)
+	assert.Equal(t, []string{"originAnnotations"}, getBuildMetadata(kOpts), nil)
+
+	// defaults are not modified
+	assert.Equal(t, "legacy", defaults["reorder"], nil)
+}
+
+func TestRunKustomizeBuildBuildMetadata(t *testing.T) {
+	rm, err := runKustomizeBuild(filesys.MakeFsOnDisk(), "test_kustomizations/basic/initial", map[string]interface{}{
+		"build_metadata": []interface{}{"originAnnotations", "managedByLabel"},
+	})
+	assert.Equal(t, nil, err, nil)
+
+	for _, r := range rm.Resources() {
+		assert.Contains(t, r.GetAnnotations(), "config.kubernetes.io/origin", nil)
+		assert.Contains(t, r.GetLabels(), "app.kubernetes.io/managed-by", nil)
+	}
+
+	// the Kustomization on disk is left untouched
+	rm, err = runKustomizeBuild(filesys.MakeFsOnDisk(), "test_kustomizations/basic/initial", nil)
+	assert.Equal(t, nil, err, nil)
+
+	for _, r := range rm.Resources() {
+		assert.NotContains(t, r.GetAnnotations(), "config.kubernetes.io/origin", nil)
+	}
+}
@@ -0,0 +1,194 @@
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	"sigs.k8s.io/kustomize/api/resmap"
+)
+
+func getGuardrailsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"allowed_namespaces": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"denied_kinds": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"forbid_cluster_scoped": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"max_resources": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+				"require_labels": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+			},
+		},
+	}
+}
+
+// Restrictions on the resources a build may contain
+// and kustomization_resource may apply.
+type guardrails struct {
+	allowedNamespaces   []string
+	deniedKinds         []string
+	forbidClusterScoped bool
+	maxResources        int
+	requireLabels       []string
+}
+
+func getGuardrails(d *schema.ResourceData) *guardrails {
+	l := d.Get("guardrails").([]interface{})
+	if len(l) == 0 || l[0] == nil {
+		return nil
+	}
+
+	m := l[0].(map[string]interface{})
+
+	return &guardrails{
+		allowedNamespaces:   convertListInterfaceToListString(m["allowed_namespaces"].([]interface{})),
+		deniedKinds:         convertListInterfaceToListString(m["denied_kinds"].([]interface{})),
+		forbidClusterScoped: m["forbid_cluster_scoped"].(bool),
+		maxResources:        m["max_resources"].(int),
+		requireLabels:       convertListInterfaceToListString(m["require_labels"].([]interface{})),
+	}
+}
+
+// Checks a single resource, max_resources only applies to builds.
+func (g *guardrails) check(kr kManifestId, labels map[string]string, clusterScoped bool) (violations []string) {
+	if len(g.allowedNamespaces) > 0 {
+		// namespaces are checked by name, because they are cluster scoped
+		ns := kr.namespace
+		if kr.group == "" && kr.kind == "Namespace" {
+			ns = kr.name
+		}
+
+		if ns != "" && !containsString(g.allowedNamespaces, ns) {
+			violations = append(violations, fmt.Sprintf("namespace %q is not in allowed_namespaces", ns))
+		}
+	}
+
+	for _, k := range g.deniedKinds {
+		// kinds with a slash also match the group
+		if k == kr.kind || k == fmt.Sprintf("%s/%s", kr.group, kr.kind) {
+			violations = append(violations, fmt.Sprintf("kind %q is in denied_kinds", k))
+		}
+	}
+
+	if g.forbidClusterScoped && clusterScoped {
+		violations = append(violations, "cluster scoped resources are forbidden")
+	}
+
+	var missing []string
+	for _, l := range g.requireLabels {
+		if _, ok := labels[l]; !ok {
+			missing = append(missing, l)
+		}
+	}
+	if len(missing) > 0 {
+		violations = append(violations, fmt.Sprintf("missing required labels: %s", strings.Join(missing, ", ")))
+	}
+
+	return violations
+}
+
+// Checks all resources of a build. Resources beyond max_resources
+// are violations too, so they are reported with all others.
+func (g *guardrails) checkResMap(rm resmap.ResMap) map[string][]string {
+	clusterScoped := make(map[string]bool)
+	for _, id := range flattenKustomizationClusterScopedIDs(rm) {
+		clusterScoped[id] = true
+	}
+
+	violations := make(map[string][]string)
+	for i, r := range rm.Resources() {
+		kr := getResourceID(r)
+
+		v := g.check(*kr, r.GetLabels(), clusterScoped[kr.string()])
+		if g.maxResources > 0 && i >= g.maxResources {
+			v = append(v, fmt.Sprintf("%d resources exceed max_resources %d", rm.Size(), g.maxResources))
+		}
+
+		if len(v) > 0 {
+			violations[kr.string()] = v
+		}
+	}
+
+	return violations
+}
+
+// Enforces the provider's and the data source's guardrails. Data
+// sources can only add restrictions, both guardrails have to pass.
+func enforceGuardrails(d *schema.ResourceData, rm resmap.ResMap, providerGuardrails *guardrails) error {
+	initOpenAPISchema()
+
+	violations := make(map[string][]string)
+	for _, g := range []*guardrails{providerGuardrails, getGuardrails(d)} {
+		if g == nil {
+			continue
+		}
+
+		for id, msgs := range g.checkResMap(rm) {
+			for _, msg := range msgs {
+				if !containsString(violations[id], msg) {
+					violations[id] = append(violations[id], msg)
+				}
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("guardrails: %s", fmtProblems(violations))
+	}
+
+	return nil
+}
+
+// Enforces the provider's guardrails for a single manifest,
+// so manifests that don't come from a build can't bypass them.
+func enforceManifestGuardrails(km *kManifest, g *guardrails) error {
+	if g == nil {
+		return nil
+	}
+
+	isNamespaced, err := km.isNamespaced()
+	if err != nil {
+		return km.fmtErr(err)
+	}
+
+	violations := g.check(km.id(), km.resource.GetLabels(), !isNamespaced)
+	if len(violations) > 0 {
+		return km.fmtErr(fmt.Errorf("guardrails: %s", strings.Join(violations, ", ")))
+	}
+
+	return nil
+}
+
+func containsString(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}
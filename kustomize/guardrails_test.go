@@ -0,0 +1,91 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func getGuardrailsTestData(t *testing.T, g map[string]interface{}) *schema.ResourceData {
+	raw := map[string]interface{}{}
+	if g != nil {
+		raw["guardrails"] = []interface{}{g}
+	}
+
+	return getBuildTestData(t, "test_kustomizations/guardrails", raw)
+}
+
+func TestGuardrailsCheck(t *testing.T) {
+	g := &guardrails{
+		allowedNamespaces:   []string{"team-a"},
+		deniedKinds:         []string{"Secret", "rbac.authorization.k8s.io/ClusterRoleBinding"},
+		forbidClusterScoped: true,
+		requireLabels:       []string{"team", "app"},
+	}
+
+	assert.Equal(t, []string{
+		`missing required labels: app`,
+	}, g.check(kManifestId{kind: "ConfigMap", namespace: "team-a", name: "test"}, map[string]string{"team": "a"}, false), nil)
+
+	assert.Equal(t, []string{
+		`namespace "team-b" is not in allowed_namespaces`,
+		`kind "Secret" is in denied_kinds`,
+	}, g.check(kManifestId{kind: "Secret", namespace: "team-b", name: "test"}, map[string]string{"team": "a", "app": "test"}, false), nil)
+
+	assert.Equal(t, []string{
+		`namespace "team-b" is not in allowed_namespaces`,
+		`cluster scoped resources are forbidden`,
+	}, g.check(kManifestId{kind: "Namespace", name: "team-b"}, map[string]string{"team": "a", "app": "test"}, true), nil)
+
+	assert.Equal(t, []string{
+		`kind "rbac.authorization.k8s.io/ClusterRoleBinding" is in denied_kinds`,
+		`cluster scoped resources are forbidden`,
+		`missing required labels: team, app`,
+	}, g.check(kManifestId{group: "rbac.authorization.k8s.io", kind: "ClusterRoleBinding", name: "test"}, nil, true), nil)
+
+	assert.Equal(t, []string(nil), (&guardrails{}).check(kManifestId{kind: "ConfigMap", namespace: "team-b", name: "test"}, nil, false), nil)
+}
+
+func TestEnforceGuardrails(t *testing.T) {
+	d := getGuardrailsTestData(t, map[string]interface{}{
+		"allowed_namespaces":    []interface{}{"team-a"},
+		"forbid_cluster_scoped": true,
+	})
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Equal(t, `kustomizationBuild: guardrails: 3 problem(s) found:
+"_/ConfigMap/team-b/test": namespace "team-b" is not in allowed_namespaces
+"_/Namespace/_/team-a": cluster scoped resources are forbidden
+"rbac.authorization.k8s.io/ClusterRoleBinding/_/test": cluster scoped resources are forbidden`, err.Error(), nil)
+
+	// filtered resources are not checked
+	d = getGuardrailsTestData(t, map[string]interface{}{
+		"require_labels": []interface{}{"team"},
+	})
+	d.Set("exclude", []interface{}{map[string]interface{}{"namespace": "team-b"}})
+
+	err = kustomizationBuild(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+}
+
+func TestEnforceGuardrailsProvider(t *testing.T) {
+	// data sources can't loosen the provider's guardrails
+	d := getGuardrailsTestData(t, map[string]interface{}{
+		"max_resources": 10,
+	})
+
+	err := kustomizationBuild(d, &Config{Guardrails: &guardrails{deniedKinds: []string{"ClusterRoleBinding"}}})
+	assert.Equal(t, `kustomizationBuild: guardrails: 1 problem(s) found:
+"rbac.authorization.k8s.io/ClusterRoleBinding/_/test": kind "ClusterRoleBinding" is in denied_kinds`, err.Error(), nil)
+
+	// max_resources is reported with the other violations
+	err = kustomizationBuild(getGuardrailsTestData(t, nil), &Config{Guardrails: &guardrails{maxResources: 3, deniedKinds: []string{"ConfigMap"}}})
+	assert.Equal(t, `kustomizationBuild: guardrails: 3 problem(s) found:
+"_/ConfigMap/team-a/test": kind "ConfigMap" is in denied_kinds
+"_/ConfigMap/team-b/test": kind "ConfigMap" is in denied_kinds
+"rbac.authorization.k8s.io/ClusterRoleBinding/_/test": 4 resources exceed max_resources 3`, err.Error(), nil)
+
+	err = kustomizationBuild(getGuardrailsTestData(t, nil), &Config{})
+	assert.Equal(t, nil, err, nil)
+}
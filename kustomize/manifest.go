@@ -89,6 +89,7 @@ type kManifest struct {
 	mapper   *restmapper.DeferredDiscoveryRESTMapper
 	client   k8sdynamic.Interface
 	json     []byte
+	origin   string
 }
 
 func newKManifest(mapper *restmapper.DeferredDiscoveryRESTMapper, client k8sdynamic.Interface) *kManifest {
@@ -99,6 +100,11 @@ func newKManifest(mapper *restmapper.DeferredDiscoveryRESTMapper, client k8sdyna
 }
 
 func (km *kManifest) load(body []byte) error {
+	body, err := manifestToJSON(body)
+	if err != nil {
+		return logError(fmt.Errorf("yaml error: %s", err))
+	}
+
 	obj, err := k8sruntime.Decode(k8sunstructured.UnstructuredJSONScheme, body)
 	if err != nil {
 		return logError(fmt.Errorf("json error: %s", err))
@@ -486,6 +492,14 @@ func (km *kManifest) waitCreatedOrUpdated(t time.Duration) error {
 }
 
 func (km *kManifest) fmtErr(err error) error {
+	if km.origin != "" {
+		return fmt.Errorf(
+			"%q (from %s): %s",
+			km.id().string(),
+			km.origin,
+			err)
+	}
+
 	return fmt.Errorf(
 		"%q: %s",
 		km.id().string(),
@@ -1,6 +1,7 @@
 package kustomize
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -32,3 +33,16 @@ func TestKManifestLoadErr(t *testing.T) {
 
 	assert.NotEqual(t, nil, err)
 }
+
+func TestKManifestFmtErrOrigin(t *testing.T) {
+	ns := []byte(`{"kind": "Namespace", "apiVersion": "v1", "metadata": {"name": "test"}}`)
+
+	km := kManifest{}
+	err := km.load(ns)
+	assert.Equal(t, nil, err)
+
+	assert.EqualError(t, km.fmtErr(errors.New("api error")), `"_/Namespace/_/test": api error`)
+
+	km.origin = "../base/namespace.yaml"
+	assert.EqualError(t, km.fmtErr(errors.New("api error")), `"_/Namespace/_/test" (from ../base/namespace.yaml): api error`)
+}
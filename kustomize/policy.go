@@ -0,0 +1,171 @@
+package kustomize
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/ext"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/resource"
+)
+
+func getPolicySchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"target": {
+					Type:     schema.TypeList,
+					Optional: true,
+					MaxItems: 1,
+					Elem:     getSelectorSchema(),
+				},
+				"expression": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"message": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+			},
+		},
+	}
+}
+
+// A CEL expression every matching resource must satisfy.
+type policyRule struct {
+	name       string
+	target     map[string]string
+	expression string
+	message    string
+	program    cel.Program
+}
+
+// The CEL environment policy expressions are compiled in. Like in
+// ValidatingAdmissionPolicies, the resource is available as object.
+func newPolicyEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		ext.Strings(),
+		ext.Sets(),
+		ext.Lists(),
+	)
+}
+
+func getPolicyRules(d *schema.ResourceData) (rules []*policyRule, err error) {
+	ps, ok := d.Get("policy").([]interface{})
+	if !ok || len(ps) == 0 {
+		return nil, nil
+	}
+
+	env, err := newPolicyEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ps {
+		if ps[i] == nil {
+			continue
+		}
+
+		p := ps[i].(map[string]interface{})
+
+		rule := &policyRule{
+			name:       p["name"].(string),
+			expression: p["expression"].(string),
+			message:    p["message"].(string),
+			target: convertMapStringInterfaceToMapStringString(
+				convertListInterfaceFirstItemToMapStringInterface(
+					p["target"].([]interface{}),
+				),
+			),
+		}
+
+		ast, iss := env.Compile(rule.expression)
+		if iss.Err() != nil {
+			return nil, fmt.Errorf("%q: invalid expression: %s", rule.name, iss.Err())
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("%q: expression must return a bool, got %s", rule.name, ast.OutputType())
+		}
+
+		rule.program, err = env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", rule.name, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// Evaluates the rule against a resource, returns an empty string if the resource passes.
+func (rule *policyRule) evaluate(r *resource.Resource) (string, error) {
+	m, err := r.Map()
+	if err != nil {
+		return "", err
+	}
+
+	out, _, err := rule.program.Eval(map[string]interface{}{"object": m})
+	if err != nil {
+		return fmt.Sprintf("policy %q: expression failed: %s", rule.name, err), nil
+	}
+
+	if out == types.True {
+		return "", nil
+	}
+	if out != types.False {
+		return fmt.Sprintf("policy %q: expression returned %v, not a bool", rule.name, out), nil
+	}
+
+	if rule.message != "" {
+		return fmt.Sprintf("policy %q: %s", rule.name, rule.message), nil
+	}
+
+	return fmt.Sprintf("policy %q: failed expression: %s", rule.name, rule.expression), nil
+}
+
+// Evaluates the policy rules against every matching resource of the
+// build. All violations fail the read at once.
+func enforcePolicies(d *schema.ResourceData, rm resmap.ResMap) error {
+	rules, err := getPolicyRules(d)
+	if err != nil {
+		return fmt.Errorf("policy: %s", err)
+	}
+
+	violations := make(map[string][]string)
+	for _, rule := range rules {
+		rs, err := rm.Select(*getSelector(rule.target))
+		if err != nil {
+			return fmt.Errorf("policy: %q: invalid target: %s", rule.name, err)
+		}
+
+		for _, r := range rs {
+			msg, err := rule.evaluate(r)
+			if err != nil {
+				return fmt.Errorf("policy: %q: %s", rule.name, err)
+			}
+
+			if msg != "" {
+				id := getResourceID(r).string()
+				violations[id] = append(violations[id], msg)
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("policy: %s", fmtProblems(violations))
+	}
+
+	return nil
+}
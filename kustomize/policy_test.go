@@ -0,0 +1,81 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func getPolicyTestData(t *testing.T, policies []interface{}) *schema.ResourceData {
+	return getBuildTestData(t, "test_kustomizations/policy", map[string]interface{}{
+		"policy": policies,
+	})
+}
+
+func TestEnforcePolicies(t *testing.T) {
+	d := getPolicyTestData(t, []interface{}{
+		map[string]interface{}{
+			"name":       "no-latest-images",
+			"target":     []interface{}{map[string]interface{}{"kind": "Deployment"}},
+			"expression": `object.spec.template.spec.containers.all(c, !c.image.endsWith(":latest"))`,
+			"message":    "images must not use the latest tag",
+		},
+		map[string]interface{}{
+			"name":       "resource-limits",
+			"target":     []interface{}{map[string]interface{}{"kind": "Deployment"}},
+			"expression": `object.spec.template.spec.containers.all(c, has(c.resources) && has(c.resources.limits))`,
+		},
+		map[string]interface{}{
+			"name":       "no-host-path",
+			"target":     []interface{}{map[string]interface{}{"kind": "Deployment"}},
+			"expression": `!has(object.spec.template.spec.volumes) || object.spec.template.spec.volumes.all(v, !has(v.hostPath))`,
+			"message":    "hostPath volumes are not allowed",
+		},
+		map[string]interface{}{
+			"name":       "replicas",
+			"expression": `object.spec.replicas >= 2`,
+		},
+	})
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Equal(t, `kustomizationBuild: policy: 4 problem(s) found:
+"_/ConfigMap/test/test": policy "replicas": expression failed: no such key: spec
+"apps/Deployment/test/latest": policy "no-latest-images": images must not use the latest tag
+"apps/Deployment/test/latest": policy "no-host-path": hostPath volumes are not allowed
+"apps/Deployment/test/pinned": policy "resource-limits": failed expression: object.spec.template.spec.containers.all(c, has(c.resources) && has(c.resources.limits))`, err.Error(), nil)
+}
+
+func TestEnforcePoliciesPass(t *testing.T) {
+	d := getPolicyTestData(t, []interface{}{
+		map[string]interface{}{
+			"name":       "namespace",
+			"expression": `object.metadata.namespace == "test"`,
+		},
+	})
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+}
+
+func TestEnforcePoliciesInvalid(t *testing.T) {
+	d := getPolicyTestData(t, []interface{}{
+		map[string]interface{}{
+			"name":       "invalid",
+			"expression": `object.metadata.name ==`,
+		},
+	})
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Regexp(t, `^kustomizationBuild: policy: "invalid": invalid expression: `, err.Error(), nil)
+
+	d = getPolicyTestData(t, []interface{}{
+		map[string]interface{}{
+			"name":       "not-bool",
+			"expression": `"string"`,
+		},
+	})
+
+	err = kustomizationBuild(d, &Config{})
+	assert.Equal(t, `kustomizationBuild: policy: "not-bool": expression must return a bool, got string`, err.Error(), nil)
+}
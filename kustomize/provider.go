@@ -3,9 +3,9 @@ package kustomize
 import (
 	"fmt"
 	"io/ioutil"
-	"sync"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/discovery/cached/memory"
@@ -22,8 +22,11 @@ import (
 type Config struct {
 	Client                dynamic.Interface
 	Mapper                *restmapper.DeferredDiscoveryRESTMapper
-	Mutex                 *sync.Mutex
+	Discovery             discovery.DiscoveryInterface
 	GzipLastAppliedConfig bool
+	BuildCache            *buildCache
+	KustomizeOptions      map[string]interface{}
+	Guardrails            *guardrails
 }
 
 // Provider ...
@@ -41,6 +44,24 @@ func Provider() *schema.Provider {
 
 			// define overlay from TF
 			"kustomization_overlay": dataSourceKustomizationOverlay(),
+
+			// parse manifests without kustomize
+			"kustomization_manifests": dataSourceKustomizationManifests(),
+
+			// read live objects from the cluster
+			"kustomization_live": dataSourceKustomizationLive(),
+
+			// discover what the cluster serves
+			"kustomization_api_resources": dataSourceKustomizationAPIResources(),
+
+			// check manifests against the cluster before applying
+			"kustomization_preflight": dataSourceKustomizationPreflight(),
+
+			// compare manifests to the cluster
+			"kustomization_diff": dataSourceKustomizationDiff(),
+
+			// compare two builds offline
+			"kustomization_build_diff": dataSourceKustomizationBuildDiff(),
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -75,6 +96,21 @@ func Provider() *schema.Provider {
 				Default:     true,
 				Description: "When 'true' compress the lastAppliedConfig annotation for resources that otherwise would exceed K8s' max annotation size. All other resources use the regular uncompressed annotation. Set to 'false' to disable compression entirely.",
 			},
+			"kustomize_options": getKustomizeOptionsSchema(),
+			"guardrails":        getGuardrailsSchema(),
+			"build_cache_dir": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("KUSTOMIZE_BUILD_CACHE_DIR", nil),
+				Description: "Directory to cache kustomization_build and kustomization_overlay results in. Results are stored as plaintext YAML, builds that contain Secrets are not cached. Caching is disabled if not set. Can be set using KUSTOMIZE_BUILD_CACHE_DIR env var",
+			},
+			"build_cache_max_size_mb": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      512,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum size of the build cache in megabytes. Least recently used entries are removed first. Set to 0 for no limit.",
+			},
 		},
 	}
 
@@ -134,16 +170,22 @@ func Provider() *schema.Provider {
 			return nil, fmt.Errorf("provider kustomization: %s", err)
 		}
 
-		mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc))
-
-		// Mutex to prevent parallel Kustomizer runs
-		// temp workaround for upstream bug
-		// https://github.com/kubernetes-sigs/kustomize/issues/3659
-		mu := &sync.Mutex{}
+		cdc := memory.NewMemCacheClient(dc)
+		mapper := restmapper.NewDeferredDiscoveryRESTMapper(cdc)
 
 		gzipLastAppliedConfig := d.Get("gzip_last_applied_config").(bool)
 
-		return &Config{client, mapper, mu, gzipLastAppliedConfig}, nil
+		buildCache, err := newBuildCache(
+			d.Get("build_cache_dir").(string),
+			d.Get("build_cache_max_size_mb").(int),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("provider kustomization: build_cache_dir: %s", err)
+		}
+
+		kustomizeOptions := getKustomizeOptionsBlock(d)
+
+		return &Config{client, mapper, cdc, gzipLastAppliedConfig, buildCache, kustomizeOptions, getGuardrails(d)}, nil
 	}
 
 	return p
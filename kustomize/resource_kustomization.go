@@ -1,6 +1,7 @@
 package kustomize
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -32,14 +33,33 @@ func kustomizationResource() *schema.Resource {
 
 		Schema: map[string]*schema.Schema{
 			"manifest": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
+				Type:             schema.TypeString,
+				Required:         true,
+				StateFunc:        manifestStateFunc,
+				DiffSuppressFunc: manifestDiffSuppressFunc,
 			},
 			"wait": &schema.Schema{
 				Type:     schema.TypeBool,
 				Default:  false,
 				Optional: true,
 			},
+			"origin": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"outputs":           getOutputsSchema(),
+			"sensitive_outputs": getOutputsSchema(),
+			"output_values": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"sensitive_output_values": &schema.Schema{
+				Type:      schema.TypeMap,
+				Computed:  true,
+				Sensitive: true,
+				Elem:      &schema.Schema{Type: schema.TypeString},
+			},
 		},
 
 		Timeouts: &schema.ResourceTimeout{
@@ -50,10 +70,42 @@ func kustomizationResource() *schema.Resource {
 	}
 }
 
+// Stores YAML manifests as JSON, like the last applied config
+// read back from the cluster, to not show a diff after apply.
+func manifestStateFunc(v interface{}) string {
+	j, err := manifestToJSON([]byte(v.(string)))
+	if err != nil {
+		return v.(string)
+	}
+
+	return string(j)
+}
+
+// Suppresses diffs of manifests that only differ in representation,
+// e.g. key order, nulls or the format of resource quantities.
+func manifestDiffSuppressFunc(k, old, new string, d *schema.ResourceData) bool {
+	if old == "" || new == "" {
+		return false
+	}
+
+	o, err := normalizeManifest([]byte(old))
+	if err != nil {
+		return false
+	}
+
+	n, err := normalizeManifest([]byte(new))
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(o, n)
+}
+
 func kustomizationResourceCreate(d *schema.ResourceData, m interface{}) error {
 	mapper := m.(*Config).Mapper
 	client := m.(*Config).Client
 	km := newKManifest(mapper, client)
+	km.origin = d.Get("origin").(string)
 
 	err := km.load([]byte(d.Get("manifest").(string)))
 	if err != nil {
@@ -66,6 +118,11 @@ func kustomizationResourceCreate(d *schema.ResourceData, m interface{}) error {
 		return logError(err)
 	}
 
+	err = enforceManifestGuardrails(km, m.(*Config).Guardrails)
+	if err != nil {
+		return logError(err)
+	}
+
 	// required for namespaced resources
 	err = km.waitNamespace(d.Timeout(schema.TimeoutCreate))
 	if err != nil {
@@ -125,6 +182,7 @@ func kustomizationResourceCreate(d *schema.ResourceData, m interface{}) error {
 
 func kustomizationResourceRead(d *schema.ResourceData, m interface{}) error {
 	km := newKManifest(m.(*Config).Mapper, m.(*Config).Client)
+	km.origin = d.Get("origin").(string)
 
 	err := km.load([]byte(d.Get("manifest").(string)))
 	if err != nil {
@@ -141,6 +199,11 @@ func kustomizationResourceRead(d *schema.ResourceData, m interface{}) error {
 
 	d.Set("manifest", getLastAppliedConfig(resp, m.(*Config).GzipLastAppliedConfig))
 
+	err = setOutputValues(d, resp)
+	if err != nil {
+		return logError(km.fmtErr(err))
+	}
+
 	return nil
 }
 
@@ -174,6 +237,11 @@ func kustomizationResourceExists(d *schema.ResourceData, m interface{}) (bool, e
 }
 
 func kustomizationResourceDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	err := setOutputValuesNewComputed(d)
+	if err != nil {
+		return logError(err)
+	}
+
 	if !d.HasChange("manifest") {
 		return nil
 	}
@@ -185,7 +253,8 @@ func kustomizationResourceDiff(ctx context.Context, d *schema.ResourceDiff, m in
 	do, dm := d.GetChange("manifest")
 
 	kmm := newKManifest(mapper, client)
-	err := kmm.load([]byte(dm.(string)))
+	kmm.origin = d.Get("origin").(string)
+	err = kmm.load([]byte(dm.(string)))
 	if err != nil {
 		return logError(err)
 	}
@@ -198,6 +267,12 @@ func kustomizationResourceDiff(ctx context.Context, d *schema.ResourceDiff, m in
 		return nil
 	}
 
+	// fail the plan, not only the apply
+	err = enforceManifestGuardrails(kmm, m.(*Config).Guardrails)
+	if err != nil {
+		return logError(err)
+	}
+
 	isNamespaced, err := kmm.isNamespaced()
 	if err != nil {
 		return logError(err)
@@ -315,17 +390,23 @@ func kustomizationResourceUpdate(d *schema.ResourceData, m interface{}) error {
 	}
 
 	kmm := newKManifest(mapper, client)
+	kmm.origin = d.Get("origin").(string)
 	err = kmm.load([]byte(dm.(string)))
 	if err != nil {
 		return logError(err)
 	}
 
-	if !d.HasChange("manifest") && !d.HasChange("wait") {
+	if !d.HasChange("manifest") && !d.HasChange("wait") && !d.HasChange("origin") && !d.HasChange("outputs") && !d.HasChange("sensitive_outputs") {
 		return logError(kmm.fmtErr(
 			errors.New("update called without diff"),
 		))
 	}
 
+	err = enforceManifestGuardrails(kmm, m.(*Config).Guardrails)
+	if err != nil {
+		return logError(err)
+	}
+
 	setLastAppliedConfig(kmo, gzipLastAppliedConfig)
 	setLastAppliedConfig(kmm, gzipLastAppliedConfig)
 
@@ -358,6 +439,7 @@ func kustomizationResourceDelete(d *schema.ResourceData, m interface{}) error {
 	mapper := m.(*Config).Mapper
 
 	km := newKManifest(mapper, client)
+	km.origin = d.Get("origin").(string)
 
 	err := parseResourceData(km, d.Get("manifest").(string))
 	if err != nil {
@@ -0,0 +1,119 @@
+package kustomize
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+func getOutputsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeMap,
+		Optional:     true,
+		Elem:         &schema.Schema{Type: schema.TypeString},
+		ValidateFunc: validateOutputs,
+	}
+}
+
+// Parses a JSONPath expression, the surrounding braces
+// kubectl requires are optional.
+func parseOutputJSONPath(name string, expr string) (*jsonpath.JSONPath, error) {
+	if !strings.HasPrefix(strings.TrimSpace(expr), "{") {
+		expr = fmt.Sprintf("{%s}", expr)
+	}
+
+	jp := jsonpath.New(name)
+	jp.AllowMissingKeys(true)
+
+	err := jp.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("output %q: invalid JSONPath %q: %s", name, expr, err)
+	}
+
+	return jp, nil
+}
+
+func validateOutputs(v interface{}, k string) (ws []string, es []error) {
+	for name, expr := range v.(map[string]interface{}) {
+		_, err := parseOutputJSONPath(name, expr.(string))
+		if err != nil {
+			es = append(es, fmt.Errorf("%s: %s", k, err))
+		}
+	}
+
+	return ws, es
+}
+
+// Evaluates the outputs against the live object. Missing fields, e.g. a
+// load balancer's status before it is provisioned, result in empty strings.
+func getOutputValues(u *k8sunstructured.Unstructured, outputs map[string]interface{}) (values map[string]string, err error) {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values = make(map[string]string)
+	for _, name := range names {
+		jp, err := parseOutputJSONPath(name, outputs[name].(string))
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		err = jp.Execute(&buf, u.Object)
+		if err != nil {
+			return nil, fmt.Errorf("output %q: %s", name, err)
+		}
+
+		values[name] = buf.String()
+	}
+
+	return values, nil
+}
+
+func setOutputValues(d *schema.ResourceData, u *k8sunstructured.Unstructured) error {
+	values, err := getOutputValues(u, d.Get("outputs").(map[string]interface{}))
+	if err != nil {
+		return err
+	}
+	d.Set("output_values", values)
+
+	sensitiveValues, err := getOutputValues(u, d.Get("sensitive_outputs").(map[string]interface{}))
+	if err != nil {
+		return err
+	}
+	d.Set("sensitive_output_values", sensitiveValues)
+
+	return nil
+}
+
+// Output values are unknown until apply, if the manifest or the outputs change.
+func setOutputValuesNewComputed(d *schema.ResourceDiff) error {
+	if !d.HasChange("manifest") && !d.HasChange("outputs") && !d.HasChange("sensitive_outputs") {
+		return nil
+	}
+
+	for k, v := range map[string]string{
+		"outputs":           "output_values",
+		"sensitive_outputs": "sensitive_output_values",
+	} {
+		o, n := d.GetChange(k)
+		if len(o.(map[string]interface{})) == 0 && len(n.(map[string]interface{})) == 0 {
+			continue
+		}
+
+		err := d.SetNewComputed(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOutputValues(t *testing.T) {
+	km := &kManifest{}
+	err := km.load([]byte(`{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "test", "namespace": "test"}, "spec": {"clusterIP": "10.0.0.1", "ports": [{"port": 80}, {"port": 443}]}, "status": {"loadBalancer": {"ingress": [{"hostname": "lb.example.com"}]}}}`))
+	assert.Equal(t, nil, err, nil)
+
+	values, err := getOutputValues(km.resource, map[string]interface{}{
+		"cluster_ip": ".spec.clusterIP",
+		"hostname":   "{.status.loadBalancer.ingress[0].hostname}",
+		"ports":      "{.spec.ports[*].port}",
+		"port":       ".spec.ports[0]",
+		"missing":    ".status.loadBalancer.ingress[0].ip",
+	})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, map[string]string{
+		"cluster_ip": "10.0.0.1",
+		"hostname":   "lb.example.com",
+		"ports":      "80 443",
+		"port":       `{"port":80}`,
+		"missing":    "",
+	}, values, nil)
+
+	_, err = getOutputValues(km.resource, map[string]interface{}{
+		"invalid": "{.spec.ports[",
+	})
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func TestValidateOutputs(t *testing.T) {
+	_, es := validateOutputs(map[string]interface{}{
+		"valid": ".spec.clusterIP",
+	}, "outputs")
+	assert.Equal(t, 0, len(es), nil)
+
+	_, es = validateOutputs(map[string]interface{}{
+		"invalid": "{.spec.ports[",
+	}, "outputs")
+	assert.Equal(t, 1, len(es), nil)
+}
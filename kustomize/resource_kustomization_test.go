@@ -990,6 +990,51 @@ resource "kustomization_resource" "sa" {
 `
 }
 
+// Outputs test
+func TestAccResourceKustomization_outputs(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceKustomizationConfig_outputs("test_kustomizations/basic/initial"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("kustomization_resource.ns", "id"),
+					resource.TestCheckResourceAttrSet("kustomization_resource.svc", "id"),
+					resource.TestCheckResourceAttr("kustomization_resource.svc", "output_values.%", "2"),
+					resource.TestCheckResourceAttrSet("kustomization_resource.svc", "output_values.cluster_ip"),
+					resource.TestCheckResourceAttr("kustomization_resource.svc", "output_values.missing", ""),
+					resource.TestCheckResourceAttr("kustomization_resource.svc", "sensitive_output_values.%", "1"),
+					resource.TestCheckResourceAttrSet("kustomization_resource.svc", "sensitive_output_values.uid"),
+				),
+			},
+		},
+	})
+}
+
+func testAccResourceKustomizationConfig_outputs(path string) string {
+	return testAccDataSourceKustomizationConfig_basic(path) + `
+resource "kustomization_resource" "ns" {
+	manifest = data.kustomization_build.test.manifests["_/Namespace/_/test-basic"]
+}
+
+resource "kustomization_resource" "svc" {
+	manifest = data.kustomization_build.test.manifests["_/Service/test-basic/test"]
+
+	outputs = {
+		cluster_ip = ".spec.clusterIP"
+		missing    = "{.status.loadBalancer.ingress[0].hostname}"
+	}
+
+	sensitive_outputs = {
+		uid = ".metadata.uid"
+	}
+
+	depends_on = [kustomization_resource.ns]
+}
+`
+}
+
 // TransformerConfigs test
 func TestAccResourceKustomization_transformerConfigs(t *testing.T) {
 
@@ -0,0 +1,527 @@
+package kustomize
+
+import (
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	k8sversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/kustomize/api/resmap"
+)
+
+//go:generate go run ./schemas/gen -o schemas/v1.34.json.gz -api v0.34.1 -apiextensions v0.34.1
+//go:generate go run ./schemas/gen -o schemas/v1.35.json.gz -api v0.35.6 -apiextensions v0.35.0
+
+// Kubernetes OpenAPI schemas, one per minor version,
+// generated from the API types by schemas/gen.
+//
+//go:embed schemas/*.json.gz
+var bundledSchemas embed.FS
+
+const objectMetaDefinition = "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"
+const quantityDefinition = "io.k8s.apimachinery.pkg.api.resource.Quantity"
+
+func getSchemaValidationSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"kube_version": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      getLatestBundledKubeVersion(),
+					ValidateFunc: validation.StringInSlice(getBundledKubeVersions(), false),
+				},
+				"schema_files": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"fail_on_errors": {
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  true,
+				},
+			},
+		},
+	}
+}
+
+// Returns the bundled Kubernetes versions, e.g. "1.35", oldest first.
+func getBundledKubeVersions() (versions []string) {
+	files, _ := fs.Glob(bundledSchemas, "schemas/v*.json.gz")
+	for _, f := range files {
+		versions = append(versions, strings.TrimSuffix(strings.TrimPrefix(f, "schemas/v"), ".json.gz"))
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return k8sversion.MustParseGeneric(versions[i]).LessThan(k8sversion.MustParseGeneric(versions[j]))
+	})
+
+	return versions
+}
+
+func getLatestBundledKubeVersion() string {
+	versions := getBundledKubeVersions()
+
+	return versions[len(versions)-1]
+}
+
+// A schema and the definitions its references resolve against.
+// CRD schemas are structural and can't have references.
+type schemaRoot struct {
+	schema      *spec.Schema
+	definitions spec.Definitions
+}
+
+// Validates manifests offline, against the bundled Kubernetes OpenAPI
+// schema, other OpenAPI documents and the openAPIV3Schema of CRDs.
+type schemaValidator struct {
+	kinds      map[k8sschema.GroupVersionKind]*schemaRoot
+	crds       map[k8sschema.GroupVersionKind]*schemaRoot
+	objectMeta *schemaRoot
+}
+
+func newSchemaValidator(kubeVersion string) (*schemaValidator, error) {
+	v := &schemaValidator{
+		kinds: make(map[k8sschema.GroupVersionKind]*schemaRoot),
+		crds:  make(map[k8sschema.GroupVersionKind]*schemaRoot),
+	}
+
+	if kubeVersion == "" {
+		kubeVersion = getLatestBundledKubeVersion()
+	}
+
+	s, err := getBundledSwagger(kubeVersion)
+	if err != nil {
+		return nil, err
+	}
+	v.addSwagger(s)
+
+	return v, nil
+}
+
+// Parsing the bundled schema is slow, keep it for
+// the lifetime of the provider.
+var bundledSwaggers = make(map[string]*spec.Swagger)
+var bundledSwaggersLock sync.Mutex
+
+func getBundledSwagger(version string) (*spec.Swagger, error) {
+	bundledSwaggersLock.Lock()
+	defer bundledSwaggersLock.Unlock()
+
+	if s, ok := bundledSwaggers[version]; ok {
+		return s, nil
+	}
+
+	data, err := bundledSchemas.ReadFile(fmt.Sprintf("schemas/v%s.json.gz", version))
+	if err != nil {
+		return nil, fmt.Errorf("no bundled schema for kube_version %q, available versions: %s", version, strings.Join(getBundledKubeVersions(), ", "))
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("reading bundled schema %q failed: %s", version, err)
+	}
+
+	j, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading bundled schema %q failed: %s", version, err)
+	}
+
+	s := &spec.Swagger{}
+	err = s.UnmarshalJSON(j)
+	if err != nil {
+		return nil, fmt.Errorf("parsing bundled schema %q failed: %s", version, err)
+	}
+
+	bundledSwaggers[version] = s
+
+	return s, nil
+}
+
+func (v *schemaValidator) addSwagger(s *spec.Swagger) {
+	for name := range s.Definitions {
+		d := s.Definitions[name]
+
+		gvks, _ := d.Extensions["x-kubernetes-group-version-kind"].([]interface{})
+		for _, e := range gvks {
+			gvk, _ := e.(map[string]interface{})
+			group, _ := gvk["group"].(string)
+			version, _ := gvk["version"].(string)
+			kind, _ := gvk["kind"].(string)
+
+			v.kinds[k8sschema.GroupVersionKind{Group: group, Version: version, Kind: kind}] = &schemaRoot{
+				schema:      &d,
+				definitions: s.Definitions,
+			}
+		}
+	}
+
+	if d, ok := s.Definitions[objectMetaDefinition]; ok {
+		v.objectMeta = &schemaRoot{schema: &d, definitions: s.Definitions}
+	}
+}
+
+func (v *schemaValidator) addCRD(m map[string]interface{}) error {
+	crdSpec, _ := m["spec"].(map[string]interface{})
+	names, _ := crdSpec["names"].(map[string]interface{})
+	group, _ := crdSpec["group"].(string)
+	kind, _ := names["kind"].(string)
+
+	// apiextensions.k8s.io/v1beta1 CRDs can have one schema for all versions
+	validation, _ := crdSpec["validation"].(map[string]interface{})
+	shared := validation["openAPIV3Schema"]
+
+	versions, _ := crdSpec["versions"].([]interface{})
+	if len(versions) == 0 {
+		if version, ok := crdSpec["version"].(string); ok {
+			versions = append(versions, map[string]interface{}{"name": version})
+		}
+	}
+
+	for _, e := range versions {
+		version, _ := e.(map[string]interface{})
+		name, _ := version["name"].(string)
+
+		vs, _ := version["schema"].(map[string]interface{})
+		s, ok := vs["openAPIV3Schema"]
+		if !ok {
+			s = shared
+		}
+		if s == nil {
+			continue
+		}
+
+		j, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+
+		root := &schemaRoot{schema: &spec.Schema{}}
+		err = json.Unmarshal(j, root.schema)
+		if err != nil {
+			return fmt.Errorf("invalid openAPIV3Schema of %s/%s %s: %s", group, kind, name, err)
+		}
+
+		v.crds[k8sschema.GroupVersionKind{Group: group, Version: name, Kind: kind}] = root
+	}
+
+	return nil
+}
+
+// Adds a local file, either an OpenAPI v2 document, e.g. the swagger.json
+// of another Kubernetes version, or YAML or JSON CRD manifests.
+func (v *schemaValidator) addSchemaFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	docs, err := splitManifests(data)
+	if err != nil {
+		return fmt.Errorf("%q: %s", path, err)
+	}
+
+	for _, j := range docs {
+		var m map[string]interface{}
+		err = json.Unmarshal(j, &m)
+		if err != nil {
+			return fmt.Errorf("%q: %s", path, err)
+		}
+
+		if _, ok := m["swagger"]; ok {
+			s := &spec.Swagger{}
+			err = s.UnmarshalJSON(j)
+			if err != nil {
+				return fmt.Errorf("%q: invalid OpenAPI document: %s", path, err)
+			}
+			v.addSwagger(s)
+
+			continue
+		}
+
+		if m["kind"] != "CustomResourceDefinition" {
+			return fmt.Errorf("%q: expected OpenAPI v2 documents or CustomResourceDefinitions, got kind %q", path, m["kind"])
+		}
+
+		err = v.addCRD(m)
+		if err != nil {
+			return fmt.Errorf("%q: %s", path, err)
+		}
+	}
+
+	return nil
+}
+
+// Validates an object, returns false for kinds without a schema.
+func (v *schemaValidator) validate(gvk k8sschema.GroupVersionKind, obj map[string]interface{}) ([]string, bool) {
+	if root, ok := v.crds[gvk]; ok {
+		return v.validateValue(root.definitions, "", root.schema, obj), true
+	}
+
+	if root, ok := v.kinds[gvk]; ok {
+		return v.validateValue(root.definitions, "", root.schema, obj), true
+	}
+
+	return nil, false
+}
+
+func (v *schemaValidator) validateValue(defs spec.Definitions, path string, s *spec.Schema, value interface{}) (errs []string) {
+	s, name, err := resolveSchemaRef(defs, s)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %s", path, err)}
+	}
+
+	// null is the same as not set
+	if value == nil {
+		return nil
+	}
+
+	// quantities are strings in the schema, but numbers are valid too
+	if name == quantityDefinition {
+		switch getJSONType(value) {
+		case "string", "integer", "number":
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: expected quantity, got %s", path, getJSONType(value))}
+	}
+
+	if s.Format == "int-or-string" || s.Extensions["x-kubernetes-int-or-string"] == true {
+		switch getJSONType(value) {
+		case "string", "integer":
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: expected integer or string, got %s", path, getJSONType(value))}
+	}
+
+	if len(s.Type) > 0 && !isJSONType(value, s.Type[0]) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", path, s.Type[0], getJSONType(value))}
+	}
+
+	if len(s.Enum) > 0 && !isInEnum(value, s.Enum) {
+		return []string{fmt.Sprintf("%s: unsupported value %q", path, fmt.Sprint(value))}
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			p := joinSchemaPath(path, k)
+
+			if ps, ok := s.Properties[k]; ok {
+				errs = append(errs, v.validateValue(defs, p, &ps, value[k])...)
+				continue
+			}
+
+			// CRD schemas don't have to include the object's type and metadata
+			if path == "" || s.Extensions["x-kubernetes-embedded-resource"] == true {
+				switch k {
+				case "apiVersion", "kind":
+					continue
+				case "metadata":
+					if v.objectMeta != nil {
+						errs = append(errs, v.validateValue(v.objectMeta.definitions, p, v.objectMeta.schema, value[k])...)
+					}
+					continue
+				}
+			}
+
+			if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+				errs = append(errs, v.validateValue(defs, p, s.AdditionalProperties.Schema, value[k])...)
+				continue
+			}
+
+			// objects without properties, e.g. RawExtension, allow any field
+			if len(s.Properties) == 0 || s.Extensions["x-kubernetes-preserve-unknown-fields"] == true {
+				continue
+			}
+			if s.AdditionalProperties != nil && s.AdditionalProperties.Allows {
+				continue
+			}
+
+			errs = append(errs, fmt.Sprintf("%s: unknown field", p))
+		}
+
+		for _, r := range s.Required {
+			if _, ok := value[r]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing required field", joinSchemaPath(path, r)))
+			}
+		}
+
+	case []interface{}:
+		if s.Items == nil || s.Items.Schema == nil {
+			return errs
+		}
+
+		for i, e := range value {
+			errs = append(errs, v.validateValue(defs, fmt.Sprintf("%s[%d]", path, i), s.Items.Schema, e)...)
+		}
+	}
+
+	return errs
+}
+
+// Resolves references lazily, because schemas like
+// JSONSchemaProps reference themselves.
+func resolveSchemaRef(defs spec.Definitions, s *spec.Schema) (*spec.Schema, string, error) {
+	var name string
+	for s.Ref.String() != "" {
+		name = strings.TrimPrefix(s.Ref.String(), "#/definitions/")
+
+		d, ok := defs[name]
+		if !ok {
+			return nil, "", fmt.Errorf("unresolved schema reference %q", s.Ref.String())
+		}
+		s = &d
+	}
+
+	return s, name, nil
+}
+
+func getJSONType(value interface{}) string {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int32, int64, json.Number:
+		return "integer"
+	case float64:
+		if value == float64(int64(value)) {
+			return "integer"
+		}
+		return "number"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+func isJSONType(value interface{}, t string) bool {
+	vt := getJSONType(value)
+	if t == "number" && vt == "integer" {
+		return true
+	}
+
+	return vt == t
+}
+
+func isInEnum(value interface{}, enum []interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func joinSchemaPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return fmt.Sprintf("%s.%s", path, key)
+}
+
+// Validates the resources of a build, if the schema_validation block is
+// set, and sets validation_errors and validation_skipped_ids. CRDs in the
+// build are used to validate custom resources.
+func setValidationErrors(d *schema.ResourceData, rm resmap.ResMap) error {
+	opts := getBlockOptions(d, "schema_validation")
+	if opts == nil {
+		d.Set("validation_errors", map[string]string{})
+		d.Set("validation_skipped_ids", []string{})
+		return nil
+	}
+
+	validationErrors, skipped, err := getValidationErrors(rm, opts)
+	if err != nil {
+		return fmt.Errorf("schema_validation: %s", err)
+	}
+	d.Set("validation_skipped_ids", skipped)
+
+	err = setProblems(d, "validation_errors", validationErrors, opts["fail_on_errors"].(bool))
+	if err != nil {
+		return fmt.Errorf("schema_validation: %s", err)
+	}
+
+	return nil
+}
+
+// Returns the validation errors by ID and the
+// IDs of resources without a schema.
+func getValidationErrors(rm resmap.ResMap, opts map[string]interface{}) (map[string][]string, []string, error) {
+	v, err := newSchemaValidator(opts["kube_version"].(string))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, f := range opts["schema_files"].([]interface{}) {
+		err = v.addSchemaFile(f.(string))
+		if err != nil {
+			return nil, nil, fmt.Errorf("schema_files: %s", err)
+		}
+	}
+
+	objs := make(map[string]map[string]interface{})
+	for _, r := range rm.Resources() {
+		m, err := r.Map()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		id := getResourceID(r).string()
+		objs[id] = m
+
+		if r.CurId().Group == "apiextensions.k8s.io" && r.CurId().Kind == "CustomResourceDefinition" {
+			err = v.addCRD(m)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%q: %s", id, err)
+			}
+		}
+	}
+
+	validationErrors := make(map[string][]string)
+	skipped := []string{}
+	for _, r := range rm.Resources() {
+		id := getResourceID(r).string()
+		gvk := k8sschema.FromAPIVersionAndKind(r.GetApiVersion(), r.GetKind())
+
+		errs, ok := v.validate(gvk, objs[id])
+		if !ok {
+			skipped = append(skipped, id)
+			continue
+		}
+
+		if len(errs) > 0 {
+			validationErrors[id] = errs
+		}
+	}
+
+	return validationErrors, skipped, nil
+}
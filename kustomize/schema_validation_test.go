@@ -0,0 +1,104 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func getSchemaValidationTestData(t *testing.T, opts map[string]interface{}) *schema.ResourceData {
+	return getBuildTestData(t, "test_kustomizations/schema_validation", map[string]interface{}{
+		"schema_validation": []interface{}{opts},
+	})
+}
+
+func TestSchemaValidation(t *testing.T) {
+	versions := getBundledKubeVersions()
+	assert.Greater(t, len(versions), 1, nil)
+
+	for _, v := range versions {
+		d := getSchemaValidationTestData(t, map[string]interface{}{
+			"kube_version":   v,
+			"fail_on_errors": false,
+		})
+
+		err := kustomizationBuild(d, &Config{})
+		assert.Equal(t, nil, err, v)
+
+		assert.Equal(t, map[string][]string{
+			"apps/Deployment/test/test": []string{
+				"metadata.labelz: unknown field",
+				"spec.replicas: expected integer, got string",
+				"spec.template.spec.containers[0].imagee: unknown field",
+				"spec.template.spec.containers[1].name: missing required field",
+			},
+			"test.example.com/Clusteredcrd/_/test": []string{
+				"spec.sise: unknown field",
+				"spec.size: missing required field",
+			},
+		}, getBuildTestJSONLists(t, d, "validation_errors"), v)
+		assert.Equal(t, []interface{}{"example.com/Unknown/_/test"}, d.Get("validation_skipped_ids").(*schema.Set).List(), v)
+	}
+}
+
+func TestSchemaValidationDefaultKubeVersion(t *testing.T) {
+	d := getSchemaValidationTestData(t, map[string]interface{}{
+		"fail_on_errors": false,
+	})
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+
+	// without a kube_version, built-in kinds are
+	// validated against the latest bundled schema
+	assert.Equal(t, getLatestBundledKubeVersion(), d.Get("schema_validation.0.kube_version"), nil)
+	assert.Equal(t, []string{
+		"metadata.labelz: unknown field",
+		"spec.replicas: expected integer, got string",
+		"spec.template.spec.containers[0].imagee: unknown field",
+		"spec.template.spec.containers[1].name: missing required field",
+	}, getBuildTestJSONLists(t, d, "validation_errors")["apps/Deployment/test/test"], nil)
+	assert.Equal(t, []interface{}{"example.com/Unknown/_/test"}, d.Get("validation_skipped_ids").(*schema.Set).List(), nil)
+}
+
+func TestSchemaValidationFailOnErrors(t *testing.T) {
+	d := getSchemaValidationTestData(t, map[string]interface{}{})
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Regexp(t, `^schema_validation: 6 problem\(s\) found:\n"apps/Deployment/test/test": metadata.labelz: unknown field\n`, err.Error(), nil)
+}
+
+func TestSchemaValidationDisabled(t *testing.T) {
+	d := getBuildTestData(t, "test_kustomizations/schema_validation", nil)
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, map[string]interface{}{}, d.Get("validation_errors"), nil)
+	assert.Equal(t, 0, d.Get("validation_skipped_ids").(*schema.Set).Len(), nil)
+}
+
+func TestSchemaValidationSchemaFiles(t *testing.T) {
+	d := getSchemaValidationTestData(t, map[string]interface{}{
+		"schema_files": []interface{}{
+			"test_kustomizations/_test_files/schema_validation_crd.yaml",
+			// a minimal OpenAPI document, replacing the bundled ConfigMap schema
+			"test_kustomizations/_test_files/schema_validation_swagger.json",
+		},
+		"fail_on_errors": false,
+	})
+
+	err := kustomizationBuild(d, &Config{})
+	assert.Equal(t, nil, err, nil)
+
+	validationErrors := getBuildTestJSONLists(t, d, "validation_errors")
+	assert.Equal(t, []string{"data: unknown field"}, validationErrors["_/ConfigMap/test/valid"], nil)
+	assert.Equal(t, []string{"spec.anything: unknown field"}, validationErrors["example.com/Unknown/_/test"], nil)
+
+	d = getSchemaValidationTestData(t, map[string]interface{}{
+		"schema_files": []interface{}{"test_kustomizations/_test_files/schema_validation_invalid.yaml"},
+	})
+
+	err = kustomizationBuild(d, &Config{})
+	assert.Regexp(t, `expected OpenAPI v2 documents or CustomResourceDefinitions, got kind "ConfigMap"$`, err.Error(), nil)
+}
@@ -0,0 +1,665 @@
+// Generates the Kubernetes OpenAPI v2 documents bundled for schema
+// validation from the sources of the API types, following the rules of
+// kube-openapi's openapi-gen. Descriptions are left out to keep the
+// documents small.
+//
+// Usage:
+//
+//	go run ./schemas/gen -o schemas/v1.35.json.gz -api v0.35.6 -apiextensions v0.35.0
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const runtimeObjectMarker = "+k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object"
+
+var versionDir = regexp.MustCompile(`^v\d+((alpha|beta)\d+)?$`)
+
+// Basic types and their OpenAPI type and format.
+var basicTypes = map[string][2]string{
+	"string":  {"string", ""},
+	"bool":    {"boolean", ""},
+	"byte":    {"integer", "byte"},
+	"int":     {"integer", ""},
+	"int8":    {"integer", "byte"},
+	"int16":   {"integer", "int32"},
+	"int32":   {"integer", "int32"},
+	"int64":   {"integer", "int64"},
+	"uint":    {"integer", ""},
+	"uint8":   {"integer", "byte"},
+	"uint16":  {"integer", "int32"},
+	"uint32":  {"integer", "int64"},
+	"uint64":  {"integer", "int64"},
+	"float32": {"number", "float"},
+	"float64": {"number", "double"},
+}
+
+type module struct {
+	path string
+	dir  string
+}
+
+type typeInfo struct {
+	spec    *ast.TypeSpec
+	doc     []string
+	imports map[string]string
+}
+
+type pkg struct {
+	path      string
+	doc       []string
+	groupName *string
+	types     map[string]*typeInfo
+	typeNames []string
+	// OpenAPISchemaType and OpenAPISchemaFormat of types that implement them
+	openAPITypes   map[string][]string
+	openAPIFormats map[string]string
+}
+
+type generator struct {
+	modules []module
+	pkgs    map[string]*pkg
+	defs    map[string]map[string]interface{}
+	// properties and required fields of struct definitions, for inlining
+	structs map[string][2]interface{}
+}
+
+func main() {
+	out := flag.String("o", "", "output file")
+	apiVersion := flag.String("api", "", "version of k8s.io/api and k8s.io/apimachinery")
+	apiextensionsVersion := flag.String("apiextensions", "", "version of k8s.io/apiextensions-apiserver")
+	flag.Parse()
+
+	if *out == "" || *apiVersion == "" || *apiextensionsVersion == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	g := &generator{
+		pkgs:    make(map[string]*pkg),
+		defs:    make(map[string]map[string]interface{}),
+		structs: make(map[string][2]interface{}),
+	}
+
+	for _, m := range []string{
+		"k8s.io/api@" + *apiVersion,
+		"k8s.io/apimachinery@" + *apiVersion,
+		"k8s.io/apiextensions-apiserver@" + *apiextensionsVersion,
+	} {
+		mod, err := downloadModule(m)
+		if err != nil {
+			log.Fatal(err)
+		}
+		g.modules = append(g.modules, mod)
+	}
+
+	for _, root := range []string{"k8s.io/api", "k8s.io/apiextensions-apiserver/pkg/apis"} {
+		err := g.addKinds(root)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err := writeSwagger(*out, *apiVersion, g.defs)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func downloadModule(m string) (module, error) {
+	cmd := exec.Command("go", "mod", "download", "-json", m)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return module{}, fmt.Errorf("downloading %s failed: %s", m, err)
+	}
+
+	var info struct {
+		Path string
+		Dir  string
+	}
+	err = json.Unmarshal(out, &info)
+	if err != nil {
+		return module{}, err
+	}
+
+	return module{path: info.Path, dir: info.Dir}, nil
+}
+
+func writeSwagger(out string, version string, defs map[string]map[string]interface{}) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// the version of k8s.io/api is v0.x.y for Kubernetes v1.x.y
+	version = strings.Replace(version, "v0.", "v1.", 1)
+
+	w := gzip.NewWriter(f)
+	err = json.NewEncoder(w).Encode(map[string]interface{}{
+		"swagger":     "2.0",
+		"info":        map[string]string{"title": "Kubernetes", "version": version},
+		"paths":       map[string]interface{}{},
+		"definitions": defs,
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+func (g *generator) dir(importPath string) (string, error) {
+	for _, m := range g.modules {
+		if importPath == m.path || strings.HasPrefix(importPath, m.path+"/") {
+			return filepath.Join(m.dir, strings.TrimPrefix(importPath, m.path)), nil
+		}
+	}
+
+	return "", fmt.Errorf("no module for package %q", importPath)
+}
+
+// Adds the definitions of all kinds of the API groups below root.
+func (g *generator) addKinds(root string) error {
+	rootDir, err := g.dir(root)
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(rootDir, func(dir string, e fs.DirEntry, err error) error {
+		if err != nil || !e.IsDir() || !versionDir.MatchString(e.Name()) {
+			return err
+		}
+
+		rel, err := filepath.Rel(rootDir, dir)
+		if err != nil {
+			return err
+		}
+
+		p, err := g.pkg(path.Join(root, filepath.ToSlash(rel)))
+		if err != nil {
+			return err
+		}
+
+		group, ok := p.group()
+		if !ok {
+			return nil
+		}
+
+		for _, name := range p.typeNames {
+			t := p.types[name]
+			if !hasLine(t.doc, runtimeObjectMarker) || hasLine(t.doc, "+k8s:openapi-gen=false") {
+				continue
+			}
+			if _, ok := t.spec.Type.(*ast.StructType); !ok {
+				continue
+			}
+
+			ref, err := g.named(p, name)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %s", p.path, name, err)
+			}
+
+			def := g.defs[strings.TrimPrefix(ref["$ref"].(string), "#/definitions/")]
+			def["x-kubernetes-group-version-kind"] = []map[string]string{{
+				"group":   group,
+				"version": e.Name(),
+				"kind":    name,
+			}}
+		}
+
+		return nil
+	})
+}
+
+func (g *generator) pkg(importPath string) (*pkg, error) {
+	if p, ok := g.pkgs[importPath]; ok {
+		return p, nil
+	}
+
+	dir, err := g.dir(importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	files, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pkg{
+		path:           importPath,
+		types:          make(map[string]*typeInfo),
+		openAPITypes:   make(map[string][]string),
+		openAPIFormats: make(map[string]string),
+	}
+
+	for _, astPkg := range files {
+		if strings.HasSuffix(astPkg.Name, "_test") {
+			continue
+		}
+
+		for _, f := range astPkg.Files {
+			// package markers are often separated from the package doc
+			for _, c := range f.Comments {
+				if c.Pos() < f.Package {
+					p.doc = append(p.doc, commentLines(c)...)
+				}
+			}
+
+			imports := make(map[string]string)
+			for _, i := range f.Imports {
+				ip, _ := strconv.Unquote(i.Path.Value)
+				name := path.Base(ip)
+				if i.Name != nil {
+					name = i.Name.Name
+				}
+				imports[name] = ip
+			}
+
+			for _, decl := range f.Decls {
+				switch decl := decl.(type) {
+				case *ast.GenDecl:
+					if decl.Tok == token.CONST {
+						p.addGroupName(decl)
+					}
+					if decl.Tok != token.TYPE {
+						continue
+					}
+					for _, s := range decl.Specs {
+						ts := s.(*ast.TypeSpec)
+						doc := ts.Doc
+						if doc == nil && len(decl.Specs) == 1 {
+							doc = decl.Doc
+						}
+
+						lines := append(getSecondClosestComment(fset, f, decl.Pos(), doc), commentLines(doc)...)
+						p.types[ts.Name.Name] = &typeInfo{spec: ts, doc: lines, imports: imports}
+						p.typeNames = append(p.typeNames, ts.Name.Name)
+					}
+				case *ast.FuncDecl:
+					p.addOpenAPIMethod(decl)
+				}
+			}
+		}
+	}
+
+	g.pkgs[importPath] = p
+
+	return p, nil
+}
+
+// Records the GroupName constant of register.go.
+func (p *pkg) addGroupName(decl *ast.GenDecl) {
+	for _, s := range decl.Specs {
+		vs := s.(*ast.ValueSpec)
+		if len(vs.Names) != 1 || vs.Names[0].Name != "GroupName" || len(vs.Values) != 1 {
+			continue
+		}
+
+		if b, ok := vs.Values[0].(*ast.BasicLit); ok && b.Kind == token.STRING {
+			name, _ := strconv.Unquote(b.Value)
+			p.groupName = &name
+		}
+	}
+}
+
+// Returns the API group of the package, from the GroupName
+// constant or the +groupName marker.
+func (p *pkg) group() (string, bool) {
+	if p.groupName != nil {
+		return *p.groupName, true
+	}
+
+	return getMarker(p.doc, "+groupName=")
+}
+
+// Records the return values of OpenAPISchemaType and OpenAPISchemaFormat
+// methods, openapi-gen uses them instead of the type's fields.
+func (p *pkg) addOpenAPIMethod(f *ast.FuncDecl) {
+	if f.Recv == nil || len(f.Recv.List) != 1 || f.Body == nil {
+		return
+	}
+
+	recv := f.Recv.List[0].Type
+	if s, ok := recv.(*ast.StarExpr); ok {
+		recv = s.X
+	}
+	ident, ok := recv.(*ast.Ident)
+	if !ok {
+		return
+	}
+
+	var ret ast.Expr
+	for _, s := range f.Body.List {
+		if r, ok := s.(*ast.ReturnStmt); ok && len(r.Results) == 1 {
+			ret = r.Results[0]
+		}
+	}
+
+	switch f.Name.Name {
+	case "OpenAPISchemaType":
+		types := []string{}
+		if lit, ok := ret.(*ast.CompositeLit); ok {
+			for _, e := range lit.Elts {
+				if b, ok := e.(*ast.BasicLit); ok {
+					s, _ := strconv.Unquote(b.Value)
+					types = append(types, s)
+				}
+			}
+		}
+		p.openAPITypes[ident.Name] = types
+	case "OpenAPISchemaFormat":
+		if b, ok := ret.(*ast.BasicLit); ok {
+			p.openAPIFormats[ident.Name], _ = strconv.Unquote(b.Value)
+		}
+	}
+}
+
+// Returns the name of the definition of a type in a package,
+// e.g. io.k8s.api.apps.v1.Deployment for k8s.io/api/apps/v1.
+func (p *pkg) definitionName(name string) string {
+	if mp, ok := getMarker(p.doc, "+k8s:openapi-model-package="); ok {
+		return mp + "." + name
+	}
+
+	parts := strings.Split(p.path, "/")
+	domain := strings.Split(parts[0], ".")
+	for i, j := 0, len(domain)-1; i < j; i, j = i+1, j-1 {
+		domain[i], domain[j] = domain[j], domain[i]
+	}
+
+	return strings.Join(append(domain, append(parts[1:], name)...), ".")
+}
+
+// Returns the schema of a type expression in a file of package p.
+func (g *generator) schema(p *pkg, imports map[string]string, expr ast.Expr) (map[string]interface{}, error) {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return g.schema(p, imports, expr.X)
+	case *ast.ArrayType:
+		if ident, ok := expr.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return map[string]interface{}{"type": "string", "format": "byte"}, nil
+		}
+
+		items, err := g.schema(p, imports, expr.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case *ast.MapType:
+		values, err := g.schema(p, imports, expr.Value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case *ast.InterfaceType:
+		return map[string]interface{}{}, nil
+	case *ast.Ident:
+		if _, ok := p.types[expr.Name]; !ok {
+			if b, ok := basicTypes[expr.Name]; ok {
+				s := map[string]interface{}{"type": b[0]}
+				if b[1] != "" {
+					s["format"] = b[1]
+				}
+				return s, nil
+			}
+		}
+		return g.named(p, expr.Name)
+	case *ast.SelectorExpr:
+		x, ok := expr.X.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %T", expr.X)
+		}
+		ip, ok := imports[x.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown package %q", x.Name)
+		}
+		other, err := g.pkg(ip)
+		if err != nil {
+			return nil, err
+		}
+		return g.named(other, expr.Sel.Name)
+	}
+
+	return nil, fmt.Errorf("unsupported type %T", expr)
+}
+
+// Returns a reference to the definition of structs and types with
+// OpenAPI methods, and the schema of other named types inline.
+func (g *generator) named(p *pkg, name string) (map[string]interface{}, error) {
+	t, ok := p.types[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %s.%s", p.path, name)
+	}
+
+	defName := p.definitionName(name)
+	ref := map[string]interface{}{"$ref": "#/definitions/" + defName}
+
+	if types, ok := p.openAPITypes[name]; ok {
+		def := map[string]interface{}{}
+		if len(types) > 0 {
+			def["type"] = types[0]
+		}
+		if f := p.openAPIFormats[name]; f != "" {
+			def["format"] = f
+		}
+		g.defs[defName] = def
+
+		return ref, nil
+	}
+
+	if t.spec.Assign.IsValid() {
+		return g.schema(p, t.imports, t.spec.Type)
+	}
+
+	if _, ok := t.spec.Type.(*ast.StructType); !ok {
+		return g.schema(p, t.imports, t.spec.Type)
+	}
+
+	_, err := g.structProperties(p, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ref, nil
+}
+
+// Generates the definition of a struct and returns its
+// properties and required fields.
+func (g *generator) structProperties(p *pkg, name string) ([2]interface{}, error) {
+	t, ok := p.types[name]
+	if !ok {
+		return [2]interface{}{}, fmt.Errorf("unknown type %s.%s", p.path, name)
+	}
+
+	// named types of structs in other packages
+	st, ok := t.spec.Type.(*ast.StructType)
+	if !ok {
+		op, on, err := g.resolve(p, t.imports, t.spec.Type)
+		if err != nil {
+			return [2]interface{}{}, err
+		}
+		return g.structProperties(op, on)
+	}
+
+	defName := p.definitionName(name)
+	if s, ok := g.structs[defName]; ok {
+		return s, nil
+	}
+
+	props := make(map[string]interface{})
+	required := []string{}
+
+	// add the definition first, structs can reference themselves
+	def := map[string]interface{}{"type": "object"}
+	g.defs[defName] = def
+	g.structs[defName] = [2]interface{}{props, required}
+
+	for _, f := range st.Fields.List {
+		tag := ""
+		if f.Tag != nil {
+			tag, _ = strconv.Unquote(f.Tag.Value)
+		}
+		jsonTag := reflect.StructTag(tag).Get("json")
+		jsonName, _, _ := strings.Cut(jsonTag, ",")
+
+		if jsonName == "-" {
+			continue
+		}
+
+		if len(f.Names) == 0 && jsonName == "" {
+			ep, en, err := g.resolve(p, t.imports, f.Type)
+			if err != nil {
+				return [2]interface{}{}, fmt.Errorf("%s: %s", name, err)
+			}
+			inlined, err := g.structProperties(ep, en)
+			if err != nil {
+				return [2]interface{}{}, err
+			}
+			for k, v := range inlined[0].(map[string]interface{}) {
+				props[k] = v
+			}
+			required = append(required, inlined[1].([]string)...)
+
+			continue
+		}
+
+		fieldName := ""
+		if len(f.Names) > 0 {
+			if !f.Names[0].IsExported() {
+				continue
+			}
+			fieldName = f.Names[0].Name
+		}
+		if jsonName == "" {
+			jsonName = fieldName
+		}
+
+		s, err := g.schema(p, t.imports, f.Type)
+		if err != nil {
+			return [2]interface{}{}, fmt.Errorf("%s.%s: %s", name, fieldName, err)
+		}
+		props[jsonName] = s
+
+		if !isOptional(commentLines(f.Doc), jsonTag) {
+			required = append(required, jsonName)
+		}
+	}
+
+	if len(props) > 0 {
+		def["properties"] = props
+	}
+	if len(required) > 0 {
+		def["required"] = required
+	}
+	g.structs[defName] = [2]interface{}{props, required}
+
+	return g.structs[defName], nil
+}
+
+// Returns the package and name of an embedded struct.
+func (g *generator) resolve(p *pkg, imports map[string]string, expr ast.Expr) (*pkg, string, error) {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return g.resolve(p, imports, expr.X)
+	case *ast.Ident:
+		return p, expr.Name, nil
+	case *ast.SelectorExpr:
+		x, ok := expr.X.(*ast.Ident)
+		if !ok {
+			return nil, "", fmt.Errorf("unsupported embedded type %T", expr.X)
+		}
+		other, err := g.pkg(imports[x.Name])
+		if err != nil {
+			return nil, "", err
+		}
+		return other, expr.Sel.Name, nil
+	}
+
+	return nil, "", fmt.Errorf("unsupported embedded type %T", expr)
+}
+
+// Same as openapi-gen, +optional and +required markers
+// take precedence over omitempty.
+func isOptional(doc []string, jsonTag string) bool {
+	if hasLine(doc, "+required") {
+		return false
+	}
+	if hasLine(doc, "+optional") {
+		return true
+	}
+
+	return strings.Contains(jsonTag, "omitempty")
+}
+
+// Like gengo, returns the comment that ends one blank line above
+// the doc comment of a type, it usually holds the markers.
+func getSecondClosestComment(fset *token.FileSet, f *ast.File, pos token.Pos, doc *ast.CommentGroup) []string {
+	if doc != nil {
+		pos = doc.Pos()
+	}
+	line := fset.Position(pos).Line
+
+	for _, c := range f.Comments {
+		if fset.Position(c.End()).Line == line-2 {
+			return commentLines(c)
+		}
+	}
+
+	return nil
+}
+
+func commentLines(c *ast.CommentGroup) []string {
+	if c == nil {
+		return nil
+	}
+
+	var lines []string
+	for _, l := range strings.Split(c.Text(), "\n") {
+		lines = append(lines, strings.TrimSpace(l))
+	}
+
+	return lines
+}
+
+func hasLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getMarker(lines []string, prefix string) (string, bool) {
+	for _, l := range lines {
+		if strings.HasPrefix(l, prefix) {
+			return strings.TrimPrefix(l, prefix), true
+		}
+	}
+
+	return "", false
+}
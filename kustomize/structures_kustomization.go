@@ -1,13 +1,45 @@
 package kustomize
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
 	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
-func flattenKustomizationIDs(rm resmap.ResMap) (ids []string, idsPrio [][]string, err error) {
-	p0 := []string{}
-	p1 := []string{}
-	p2 := []string{}
+const originAnnotation = "config.kubernetes.io/origin"
+
+func flattenKustomizationIDs(rm resmap.ResMap, defaults string, rules []priorityRule) (ids []string, idsPrio [][]string, err error) {
+	// the first matching rule wins
+	tiers := make(map[resid.ResId]int)
+	n := getDefaultPriorityTiers(defaults)
+	for i, rule := range rules {
+		rs, err := rm.Select(*rule.selector)
+		if err != nil {
+			return nil, nil, fmt.Errorf("priority_rules.%d: invalid target: %s", i, err)
+		}
+
+		for _, r := range rs {
+			if _, ok := tiers[r.CurId()]; !ok {
+				tiers[r.CurId()] = rule.tier
+			}
+		}
+
+		if rule.tier >= n {
+			n = rule.tier + 1
+		}
+	}
+
+	idsPrio = make([][]string, n)
+	for i := range idsPrio {
+		idsPrio[i] = []string{}
+	}
+
 	for _, id := range rm.AllIds() {
 		kr := &kManifestId{
 			group:     id.Group,
@@ -18,20 +50,13 @@ func flattenKustomizationIDs(rm resmap.ResMap) (ids []string, idsPrio [][]string
 
 		ids = append(ids, kr.string())
 
-		p := determinePrefix(kr)
-		if p < 5 {
-			p0 = append(p0, kr.string())
-		} else if p == 9 {
-			p2 = append(p2, kr.string())
-		} else {
-			p1 = append(p1, kr.string())
+		p, ok := tiers[id]
+		if !ok {
+			p = getDefaultPriority(defaults, kr)
 		}
+		idsPrio[p] = append(idsPrio[p], kr.string())
 	}
 
-	idsPrio = append(idsPrio, p0)
-	idsPrio = append(idsPrio, p1)
-	idsPrio = append(idsPrio, p2)
-
 	return ids, idsPrio, nil
 }
 
@@ -53,3 +78,536 @@ func flattenKustomizationResources(rm resmap.ResMap) (res map[string]string, err
 	}
 	return res, nil
 }
+
+func flattenKustomizationOrigins(rm resmap.ResMap) (res map[string]string, err error) {
+	res = make(map[string]string)
+	for _, r := range rm.Resources() {
+		kr := &kManifestId{
+			group:     r.CurId().Group,
+			kind:      r.CurId().Kind,
+			namespace: r.GetNamespace(),
+			name:      r.GetName(),
+		}
+
+		o, err := r.GetOrigin()
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", kr.string(), err)
+		}
+		if o == nil {
+			continue
+		}
+
+		res[kr.string()] = formatOrigin(o)
+	}
+	return res, nil
+}
+
+// Formats an origin as the path of the source file, prefixed with the
+// repository for remote resources. Generated resources, e.g. from Helm
+// charts, are reported as the Kustomization and generator that produced them.
+func formatOrigin(o *resource.Origin) (s string) {
+	if o.ConfiguredIn != "" {
+		s = o.ConfiguredIn
+		if o.ConfiguredBy.Kind != "" {
+			s = fmt.Sprintf("%s (%s %s)", s, o.ConfiguredBy.Kind, o.ConfiguredBy.Name)
+		}
+	} else {
+		s = o.Path
+	}
+
+	if o.Repo != "" {
+		s = fmt.Sprintf("%s//%s", o.Repo, s)
+		if o.Ref != "" {
+			s = fmt.Sprintf("%s?ref=%s", s, o.Ref)
+		}
+	}
+
+	return s
+}
+
+func stripOriginAnnotations(rm resmap.ResMap) error {
+	for _, r := range rm.Resources() {
+		annotations := r.GetAnnotations()
+		if _, ok := annotations[originAnnotation]; !ok {
+			continue
+		}
+
+		delete(annotations, originAnnotation)
+
+		err := r.SetAnnotations(annotations)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getResourceID(r *resource.Resource) *kManifestId {
+	return &kManifestId{
+		group:     r.CurId().Group,
+		kind:      r.CurId().Kind,
+		namespace: r.GetNamespace(),
+		name:      r.GetName(),
+	}
+}
+
+// Namespaces resources are in and namespaces defined in the build.
+func flattenKustomizationNamespaces(rm resmap.ResMap) (namespaces []string) {
+	seen := make(map[string]bool)
+	for _, r := range rm.Resources() {
+		ns := r.GetNamespace()
+		if r.CurId().Group == "" && r.CurId().Kind == "Namespace" {
+			ns = r.GetName()
+		}
+
+		if ns == "" || seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		namespaces = append(namespaces, ns)
+	}
+
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// Maps each kind to a JSON encoded list of IDs of that kind.
+func flattenKustomizationKinds(rm resmap.ResMap) (kinds map[string]string, err error) {
+	ids := make(map[string][]string)
+	for _, r := range rm.Resources() {
+		k := r.CurId().Kind
+		ids[k] = append(ids[k], getResourceID(r).string())
+	}
+
+	kinds = make(map[string]string)
+	for k, v := range ids {
+		sort.Strings(v)
+
+		j, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		kinds[k] = string(j)
+	}
+
+	return kinds, nil
+}
+
+// Images of all containers, init containers and ephemeral containers.
+// Any object with a containers list is treated as a pod spec, this
+// includes pod templates in custom resources.
+func flattenKustomizationImages(rm resmap.ResMap) (images []string, err error) {
+	seen := make(map[string]bool)
+	for _, r := range rm.Resources() {
+		m, err := r.Map()
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", getResourceID(r).string(), err)
+		}
+
+		for _, i := range findContainerImages(m) {
+			if seen[i] {
+				continue
+			}
+			seen[i] = true
+			images = append(images, i)
+		}
+	}
+
+	sort.Strings(images)
+	return images, nil
+}
+
+func findContainerImages(v interface{}) (images []string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, c := range t {
+			if k == "containers" || k == "initContainers" || k == "ephemeralContainers" {
+				if l, ok := c.([]interface{}); ok {
+					for _, e := range l {
+						if cm, ok := e.(map[string]interface{}); ok {
+							if i, ok := cm["image"].(string); ok && i != "" {
+								images = append(images, i)
+							}
+						}
+					}
+				}
+			}
+
+			images = append(images, findContainerImages(c)...)
+		}
+	case []interface{}:
+		for _, e := range t {
+			images = append(images, findContainerImages(e)...)
+		}
+	}
+
+	return images
+}
+
+// IDs of cluster scoped resources. The scope of built-in kinds is
+// looked up in kustomize's OpenAPI schema, for custom resources in
+// CRDs that are part of the build. Resources of unknown kinds are
+// considered cluster scoped if they have no namespace.
+func flattenKustomizationClusterScopedIDs(rm resmap.ResMap) (ids []string) {
+	crdScopes := make(map[string]bool)
+	for _, r := range rm.Resources() {
+		if r.CurId().Group != "apiextensions.k8s.io" || r.CurId().Kind != "CustomResourceDefinition" {
+			continue
+		}
+
+		m, err := r.Map()
+		if err != nil {
+			continue
+		}
+		spec, _ := m["spec"].(map[string]interface{})
+		names, _ := spec["names"].(map[string]interface{})
+		group, _ := spec["group"].(string)
+		kind, _ := names["kind"].(string)
+		scope, _ := spec["scope"].(string)
+
+		crdScopes[fmt.Sprintf("%s/%s", group, kind)] = scope == "Cluster"
+	}
+
+	for _, r := range rm.Resources() {
+		isClusterScoped, found := crdScopes[fmt.Sprintf("%s/%s", r.CurId().Group, r.CurId().Kind)]
+
+		if !found {
+			openAPISchemaLock.RLock()
+			isNamespaced, known := openapi.IsNamespaceScoped(kyaml.TypeMeta{
+				APIVersion: r.GetApiVersion(),
+				Kind:       r.GetKind(),
+			})
+			openAPISchemaLock.RUnlock()
+
+			if known {
+				isClusterScoped = !isNamespaced
+			} else {
+				isClusterScoped = r.GetNamespace() == ""
+			}
+		}
+
+		if isClusterScoped {
+			ids = append(ids, getResourceID(r).string())
+		}
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+// A reference to another resource, by group, kind, namespace and name.
+type objectRef struct {
+	group     string
+	kind      string
+	namespace string
+	name      string
+}
+
+// Map of IDs to a JSON encoded list of the IDs of the resources they
+// reference. Edges are derived from well-known reference fields, similar
+// to kustomize's name reference config, from custom resources to their
+// CRD and from namespaced resources to their Namespace. Only references
+// to resources that are part of the build are included.
+func flattenKustomizationGraph(rm resmap.ResMap) (graph map[string]string, err error) {
+	index := make(map[objectRef][]string)
+	crds := make(map[string]string)
+	namespaces := make(map[string]string)
+	for _, r := range rm.Resources() {
+		id := getResourceID(r).string()
+		ref := objectRef{group: r.CurId().Group, kind: r.CurId().Kind, namespace: r.GetNamespace(), name: r.GetName()}
+		index[ref] = append(index[ref], id)
+
+		if r.CurId().Group == "" && r.CurId().Kind == "Namespace" {
+			namespaces[r.GetName()] = id
+		}
+
+		if r.CurId().Group == "apiextensions.k8s.io" && r.CurId().Kind == "CustomResourceDefinition" {
+			m, err := r.Map()
+			if err != nil {
+				return nil, fmt.Errorf("%q: %s", id, err)
+			}
+			group, _ := nestedString(m, "spec", "group")
+			kind, _ := nestedString(m, "spec", "names", "kind")
+			crds[fmt.Sprintf("%s/%s", group, kind)] = id
+		}
+	}
+
+	graph = make(map[string]string)
+	for _, r := range rm.Resources() {
+		id := getResourceID(r).string()
+
+		m, err := r.Map()
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", id, err)
+		}
+
+		seen := make(map[string]bool)
+		var deps []string
+		add := func(dep string) {
+			if dep == "" || dep == id || seen[dep] {
+				return
+			}
+			seen[dep] = true
+			deps = append(deps, dep)
+		}
+
+		for _, ref := range findObjectRefs(r.CurId().Group, r.CurId().Kind, r.GetNamespace(), m) {
+			for _, dep := range index[ref] {
+				add(dep)
+			}
+		}
+		add(crds[fmt.Sprintf("%s/%s", r.CurId().Group, r.CurId().Kind)])
+		if r.GetNamespace() != "" {
+			add(namespaces[r.GetNamespace()])
+		}
+
+		sort.Strings(deps)
+		if deps == nil {
+			deps = []string{}
+		}
+
+		j, err := json.Marshal(deps)
+		if err != nil {
+			return nil, err
+		}
+		graph[id] = string(j)
+	}
+
+	return graph, nil
+}
+
+func findObjectRefs(group string, kind string, namespace string, m map[string]interface{}) (refs []objectRef) {
+	ref := func(group, kind, ns, name string) {
+		if name != "" {
+			refs = append(refs, objectRef{group: group, kind: kind, namespace: ns, name: name})
+		}
+	}
+
+	serviceRef := func(v interface{}, fields ...string) {
+		svc, _ := nestedMap(asMap(v), fields...)
+		ns, _ := svc["namespace"].(string)
+		name, _ := svc["name"].(string)
+		ref("", "Service", ns, name)
+	}
+
+	switch fmt.Sprintf("%s/%s", group, kind) {
+	case "rbac.authorization.k8s.io/RoleBinding", "rbac.authorization.k8s.io/ClusterRoleBinding":
+		rg, _ := nestedString(m, "roleRef", "apiGroup")
+		rk, _ := nestedString(m, "roleRef", "kind")
+		rn, _ := nestedString(m, "roleRef", "name")
+		if rk == "ClusterRole" {
+			ref(rg, rk, "", rn)
+		} else {
+			ref(rg, rk, namespace, rn)
+		}
+
+		subjects, _ := nestedSlice(m, "subjects")
+		for _, s := range subjects {
+			sm := asMap(s)
+			if sm["kind"] != "ServiceAccount" {
+				continue
+			}
+			ns, _ := sm["namespace"].(string)
+			if ns == "" {
+				ns = namespace
+			}
+			name, _ := sm["name"].(string)
+			ref("", "ServiceAccount", ns, name)
+		}
+	case "autoscaling/HorizontalPodAutoscaler":
+		tv, _ := nestedString(m, "spec", "scaleTargetRef", "apiVersion")
+		tk, _ := nestedString(m, "spec", "scaleTargetRef", "kind")
+		tn, _ := nestedString(m, "spec", "scaleTargetRef", "name")
+		tg, _ := resid.ParseGroupVersion(tv)
+		ref(tg, tk, namespace, tn)
+	case "networking.k8s.io/Ingress":
+		ic, _ := nestedString(m, "spec", "ingressClassName")
+		ref("networking.k8s.io", "IngressClass", "", ic)
+
+		db, _ := nestedString(m, "spec", "defaultBackend", "service", "name")
+		ref("", "Service", namespace, db)
+
+		rules, _ := nestedSlice(m, "spec", "rules")
+		for _, rule := range rules {
+			paths, _ := nestedSlice(asMap(rule), "http", "paths")
+			for _, p := range paths {
+				svc, _ := nestedString(asMap(p), "backend", "service", "name")
+				ref("", "Service", namespace, svc)
+			}
+		}
+
+		tls, _ := nestedSlice(m, "spec", "tls")
+		for _, t := range tls {
+			s, _ := asMap(t)["secretName"].(string)
+			ref("", "Secret", namespace, s)
+		}
+	case "/PersistentVolumeClaim":
+		sc, _ := nestedString(m, "spec", "storageClassName")
+		ref("storage.k8s.io", "StorageClass", "", sc)
+
+		pv, _ := nestedString(m, "spec", "volumeName")
+		ref("", "PersistentVolume", "", pv)
+	case "/PersistentVolume":
+		sc, _ := nestedString(m, "spec", "storageClassName")
+		ref("storage.k8s.io", "StorageClass", "", sc)
+	case "apps/StatefulSet":
+		svc, _ := nestedString(m, "spec", "serviceName")
+		ref("", "Service", namespace, svc)
+
+		vcts, _ := nestedSlice(m, "spec", "volumeClaimTemplates")
+		for _, vct := range vcts {
+			sc, _ := nestedString(asMap(vct), "spec", "storageClassName")
+			ref("storage.k8s.io", "StorageClass", "", sc)
+		}
+	case "/ServiceAccount":
+		for _, f := range []string{"secrets", "imagePullSecrets"} {
+			secrets, _ := nestedSlice(m, f)
+			for _, s := range secrets {
+				name, _ := asMap(s)["name"].(string)
+				ref("", "Secret", namespace, name)
+			}
+		}
+	case "admissionregistration.k8s.io/MutatingWebhookConfiguration", "admissionregistration.k8s.io/ValidatingWebhookConfiguration":
+		webhooks, _ := nestedSlice(m, "webhooks")
+		for _, w := range webhooks {
+			serviceRef(w, "clientConfig", "service")
+		}
+	case "apiregistration.k8s.io/APIService":
+		serviceRef(m, "spec", "service")
+	case "apiextensions.k8s.io/CustomResourceDefinition":
+		serviceRef(m, "spec", "conversion", "webhook", "clientConfig", "service")
+	}
+
+	return append(refs, findPodSpecRefs(namespace, m)...)
+}
+
+// References from pod specs. Like for container images, any object
+// with a containers list is treated as a pod spec.
+func findPodSpecRefs(namespace string, v interface{}) (refs []objectRef) {
+	ref := func(group, kind, ns, name string) {
+		if name != "" {
+			refs = append(refs, objectRef{group: group, kind: kind, namespace: ns, name: name})
+		}
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if _, ok := t["containers"].([]interface{}); ok {
+			for _, f := range []string{"serviceAccountName", "serviceAccount"} {
+				sa, _ := t[f].(string)
+				ref("", "ServiceAccount", namespace, sa)
+			}
+
+			pc, _ := t["priorityClassName"].(string)
+			ref("scheduling.k8s.io", "PriorityClass", "", pc)
+
+			rc, _ := t["runtimeClassName"].(string)
+			ref("node.k8s.io", "RuntimeClass", "", rc)
+
+			ips, _ := t["imagePullSecrets"].([]interface{})
+			for _, s := range ips {
+				name, _ := asMap(s)["name"].(string)
+				ref("", "Secret", namespace, name)
+			}
+
+			volumes, _ := t["volumes"].([]interface{})
+			for _, vol := range volumes {
+				vm := asMap(vol)
+
+				cm, _ := nestedString(vm, "configMap", "name")
+				ref("", "ConfigMap", namespace, cm)
+
+				s, _ := nestedString(vm, "secret", "secretName")
+				ref("", "Secret", namespace, s)
+
+				pvc, _ := nestedString(vm, "persistentVolumeClaim", "claimName")
+				ref("", "PersistentVolumeClaim", namespace, pvc)
+
+				sources, _ := nestedSlice(vm, "projected", "sources")
+				for _, src := range sources {
+					cm, _ := nestedString(asMap(src), "configMap", "name")
+					ref("", "ConfigMap", namespace, cm)
+
+					s, _ := nestedString(asMap(src), "secret", "name")
+					ref("", "Secret", namespace, s)
+				}
+			}
+
+			for _, f := range []string{"containers", "initContainers", "ephemeralContainers"} {
+				containers, _ := t[f].([]interface{})
+				for _, c := range containers {
+					cm := asMap(c)
+
+					env, _ := cm["env"].([]interface{})
+					for _, e := range env {
+						cmr, _ := nestedString(asMap(e), "valueFrom", "configMapKeyRef", "name")
+						ref("", "ConfigMap", namespace, cmr)
+
+						sr, _ := nestedString(asMap(e), "valueFrom", "secretKeyRef", "name")
+						ref("", "Secret", namespace, sr)
+					}
+
+					envFrom, _ := cm["envFrom"].([]interface{})
+					for _, e := range envFrom {
+						cmr, _ := nestedString(asMap(e), "configMapRef", "name")
+						ref("", "ConfigMap", namespace, cmr)
+
+						sr, _ := nestedString(asMap(e), "secretRef", "name")
+						ref("", "Secret", namespace, sr)
+					}
+				}
+			}
+		}
+
+		for _, c := range t {
+			refs = append(refs, findPodSpecRefs(namespace, c)...)
+		}
+	case []interface{}:
+		for _, e := range t {
+			refs = append(refs, findPodSpecRefs(namespace, e)...)
+		}
+	}
+
+	return refs
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+// Unlike the unstructured helpers, these don't deep copy
+// and work with the int values of resource maps.
+func nestedField(m map[string]interface{}, fields ...string) (v interface{}, found bool) {
+	v = m
+	for _, f := range fields {
+		vm, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok = vm[f]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+func nestedString(m map[string]interface{}, fields ...string) (string, bool) {
+	v, _ := nestedField(m, fields...)
+	s, ok := v.(string)
+	return s, ok
+}
+
+func nestedSlice(m map[string]interface{}, fields ...string) ([]interface{}, bool) {
+	v, _ := nestedField(m, fields...)
+	l, ok := v.([]interface{})
+	return l, ok
+}
+
+func nestedMap(m map[string]interface{}, fields ...string) (map[string]interface{}, bool) {
+	v, _ := nestedField(m, fields...)
+	vm, ok := v.(map[string]interface{})
+	return vm, ok
+}
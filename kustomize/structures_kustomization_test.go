@@ -5,7 +5,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/api/types"
 	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
 func TestConvertKustomizationIDs(t *testing.T) {
@@ -16,7 +20,7 @@ func TestConvertKustomizationIDs(t *testing.T) {
 	rm, err := k.Run(fSys, "test_kustomizations/basic/initial")
 	assert.Equal(t, err, nil, nil)
 
-	ids, idsPrio, err := flattenKustomizationIDs(rm)
+	ids, idsPrio, err := flattenKustomizationIDs(rm, priorityDefaultsLegacy, nil)
 	assert.Equal(t, err, nil, nil)
 
 	expMerged := append(idsPrio[0], idsPrio[1]...)
@@ -35,3 +39,270 @@ func TestConvertKustomizationIDs(t *testing.T) {
 	expP3 := []string{}
 	assert.ElementsMatch(t, expP3, idsPrio[2], nil)
 }
+
+func TestConvertKustomizationIDsPriorityRules(t *testing.T) {
+	fSys := filesys.MakeFsOnDisk()
+	opts := krusty.MakeDefaultOptions()
+	k := krusty.MakeKustomizer(opts)
+
+	rm, err := k.Run(fSys, "test_kustomizations/basic/initial")
+	assert.Equal(t, err, nil, nil)
+
+	_, idsPrio, err := flattenKustomizationIDs(rm, priorityDefaultsHelm, nil)
+	assert.Equal(t, err, nil, nil)
+	assert.Equal(t, 5, len(idsPrio), nil)
+	assert.ElementsMatch(t, []string{"_/Namespace/_/test-basic"}, idsPrio[0], nil)
+	assert.ElementsMatch(t, []string{"apps/Deployment/test-basic/test", "networking.k8s.io/Ingress/test-basic/test", "_/Service/test-basic/test"}, idsPrio[2], nil)
+
+	rules := []priorityRule{
+		{selector: &types.Selector{LabelSelector: "app=test"}, tier: 7},
+		{selector: &types.Selector{ResId: resid.ResId{Gvk: resid.Gvk{Kind: "Deployment"}}}, tier: 0},
+		{selector: &types.Selector{ResId: resid.ResId{Gvk: resid.Gvk{Group: "networking.k8s.io"}}}, tier: 3},
+	}
+
+	_, idsPrio, err = flattenKustomizationIDs(rm, priorityDefaultsLegacy, rules)
+	assert.Equal(t, err, nil, nil)
+	assert.Equal(t, 8, len(idsPrio), nil)
+	assert.ElementsMatch(t, []string{"_/Namespace/_/test-basic"}, idsPrio[0], nil)
+	assert.ElementsMatch(t, []string{}, idsPrio[1], nil)
+	assert.ElementsMatch(t, []string{}, idsPrio[2], nil)
+	assert.ElementsMatch(t, []string{"networking.k8s.io/Ingress/test-basic/test"}, idsPrio[3], nil)
+	assert.ElementsMatch(t, []string{"apps/Deployment/test-basic/test", "_/Service/test-basic/test"}, idsPrio[7], nil)
+
+	rules = []priorityRule{
+		{selector: &types.Selector{LabelSelector: "app in (("}, tier: 0},
+	}
+
+	_, _, err = flattenKustomizationIDs(rm, priorityDefaultsLegacy, rules)
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func TestFlattenKustomizationOrigins(t *testing.T) {
+	rm, err := runKustomizeBuild(filesys.MakeFsOnDisk(), "test_kustomizations/basic/initial", map[string]interface{}{
+		"build_metadata": []interface{}{"originAnnotations"},
+	})
+	assert.Equal(t, nil, err, nil)
+
+	origins, err := flattenKustomizationOrigins(rm)
+	assert.Equal(t, nil, err, nil)
+
+	expOrigins := map[string]string{
+		"_/Namespace/_/test-basic":                  "namespace.yaml",
+		"apps/Deployment/test-basic/test":           "../../_example_app/deployment.yaml",
+		"networking.k8s.io/Ingress/test-basic/test": "../../_example_app/ingress.yaml",
+		"_/Service/test-basic/test":                 "../../_example_app/service.yaml",
+	}
+	assert.Equal(t, expOrigins, origins, nil)
+
+	err = stripOriginAnnotations(rm)
+	assert.Equal(t, nil, err, nil)
+
+	origins, err = flattenKustomizationOrigins(rm)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, map[string]string{}, origins, nil)
+
+	manifests, err := flattenKustomizationResources(rm)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"test-basic"}}`, manifests["_/Namespace/_/test-basic"], nil)
+}
+
+func TestFormatOrigin(t *testing.T) {
+	o := &resource.Origin{
+		Path: "examples/helloWorld/deployment.yaml",
+		Repo: "https://github.com/kubernetes-sigs/kustomize",
+		Ref:  "v1.0.6",
+	}
+	assert.Equal(t, "https://github.com/kubernetes-sigs/kustomize//examples/helloWorld/deployment.yaml?ref=v1.0.6", formatOrigin(o), nil)
+
+	o = &resource.Origin{
+		ConfiguredIn: "kustomization.yaml",
+		ConfiguredBy: kyaml.ResourceIdentifier{
+			TypeMeta: kyaml.TypeMeta{APIVersion: "builtin", Kind: "HelmChartInflationGenerator"},
+			NameMeta: kyaml.NameMeta{Name: "minecraft"},
+		},
+	}
+	assert.Equal(t, "kustomization.yaml (HelmChartInflationGenerator minecraft)", formatOrigin(o), nil)
+}
+
+func TestFlattenKustomizationInventory(t *testing.T) {
+	rm, err := runKustomizeBuild(filesys.MakeFsOnDisk(), "test_kustomizations/crd/initial", nil)
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, []string{"test-crd"}, flattenKustomizationNamespaces(rm), nil)
+
+	kinds, err := flattenKustomizationKinds(rm)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, map[string]string{
+		"Namespace":                `["_/Namespace/_/test-crd"]`,
+		"CustomResourceDefinition": `["apiextensions.k8s.io/CustomResourceDefinition/_/clusteredcrds.test.example.com","apiextensions.k8s.io/CustomResourceDefinition/_/namespacedcrds.test.example.com"]`,
+		"Namespacedcrd":            `["test.example.com/Namespacedcrd/test-crd/namespacedco"]`,
+		"Clusteredcrd":             `["test.example.com/Clusteredcrd/_/clusteredco"]`,
+	}, kinds, nil)
+
+	expClusterScoped := []string{
+		"_/Namespace/_/test-crd",
+		"apiextensions.k8s.io/CustomResourceDefinition/_/clusteredcrds.test.example.com",
+		"apiextensions.k8s.io/CustomResourceDefinition/_/namespacedcrds.test.example.com",
+		"test.example.com/Clusteredcrd/_/clusteredco",
+	}
+	assert.Equal(t, expClusterScoped, flattenKustomizationClusterScopedIDs(rm), nil)
+}
+
+func TestFlattenKustomizationImages(t *testing.T) {
+	fSys, err := makeInMemoryFS(map[string]string{
+		"kustomization.yaml": "resources:\n- resources.yaml\n",
+		"resources.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.36
+      containers:
+      - name: app
+        image: nginx:1.25
+      - name: sidecar
+        image: envoyproxy/envoy:v1.28
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: test
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: job
+            image: busybox:1.36
+---
+apiVersion: example.com/v1
+kind: Workload
+metadata:
+  name: test
+spec:
+  podTemplate:
+    spec:
+      containers:
+      - name: custom
+        image: example.com/custom:latest
+`,
+	})
+	assert.Equal(t, nil, err, nil)
+
+	rm, err := runKustomizeBuild(fSys, ".", nil)
+	assert.Equal(t, nil, err, nil)
+
+	images, err := flattenKustomizationImages(rm)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string{"busybox:1.36", "envoyproxy/envoy:v1.28", "example.com/custom:latest", "nginx:1.25"}, images, nil)
+}
+
+func TestFlattenKustomizationGraph(t *testing.T) {
+	fSys, err := makeInMemoryFS(map[string]string{
+		"kustomization.yaml": "namespace: test\nresources:\n- resources.yaml\n",
+		"resources.yaml": `apiVersion: v1
+kind: Namespace
+metadata:
+  name: test
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: example.com/v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: app
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: app
+subjects:
+- kind: ServiceAccount
+  name: app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 2
+  template:
+    spec:
+      serviceAccountName: app
+      containers:
+      - name: app
+        image: nginx
+        envFrom:
+        - configMapRef:
+            name: config
+        - secretRef:
+            name: external
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: data
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  scope: Namespaced
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: test
+`,
+	})
+	assert.Equal(t, nil, err, nil)
+
+	rm, err := runKustomizeBuild(fSys, "/", nil)
+	assert.Equal(t, nil, err, nil)
+
+	graph, err := flattenKustomizationGraph(rm)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, map[string]string{
+		"_/Namespace/_/test":                                                  `[]`,
+		"_/ServiceAccount/test/app":                                           `["_/Namespace/_/test"]`,
+		"_/ConfigMap/test/config":                                             `["_/Namespace/_/test"]`,
+		"example.com/ConfigMap/test/config":                                   `["_/Namespace/_/test"]`,
+		"_/PersistentVolumeClaim/test/data":                                   `["_/Namespace/_/test"]`,
+		"rbac.authorization.k8s.io/Role/test/app":                             `["_/Namespace/_/test"]`,
+		"rbac.authorization.k8s.io/RoleBinding/test/app":                      `["_/Namespace/_/test","_/ServiceAccount/test/app","rbac.authorization.k8s.io/Role/test/app"]`,
+		"apps/Deployment/test/app":                                            `["_/ConfigMap/test/config","_/Namespace/_/test","_/PersistentVolumeClaim/test/data","_/ServiceAccount/test/app"]`,
+		"apiextensions.k8s.io/CustomResourceDefinition/_/widgets.example.com": `[]`,
+		"example.com/Widget/test/test":                                        `["_/Namespace/_/test","apiextensions.k8s.io/CustomResourceDefinition/_/widgets.example.com"]`,
+	}, graph, nil)
+}
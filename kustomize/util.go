@@ -1,16 +1,20 @@
 package kustomize
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"runtime"
+	"strconv"
 	"strings"
 
 	k8scorev1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
 	k8svalidation "k8s.io/apimachinery/pkg/api/validation"
 	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
@@ -20,19 +24,246 @@ import (
 	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
 	"k8s.io/apimachinery/pkg/util/mergepatch"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/kube-openapi/pkg/validation/spec"
 	"k8s.io/kubectl/pkg/scheme"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
 const lastAppliedConfigAnnotation = k8scorev1.LastAppliedConfigAnnotation
 const gzipLastAppliedConfigAnnotation = "kustomization.kubestack.com/last-applied-config-gzip"
 
+// Converts a YAML manifest to JSON. JSON manifests are returned
+// unchanged, to keep the last applied config of existing resources.
+func manifestToJSON(body []byte) ([]byte, error) {
+	docs, err := splitManifests(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(docs) != 1 {
+		return nil, fmt.Errorf("expected exactly one manifest, got %d", len(docs))
+	}
+
+	return docs[0], nil
+}
+
+// Splits a YAML stream into JSON documents. JSON is
+// returned unchanged, as a single document.
+func splitManifests(body []byte) ([][]byte, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return [][]byte{body}, nil
+	}
+
+	var docs [][]byte
+	r := k8syaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(body)))
+	for {
+		doc, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		j, err := k8syaml.ToJSON(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		// skip empty documents, e.g. after a trailing separator
+		if bytes.Equal(j, []byte("null")) {
+			continue
+		}
+
+		docs = append(docs, j)
+	}
+
+	return docs, nil
+}
+
+// Keys where an empty map is not the same as no value,
+// e.g. an emptyDir volume or a selector matching all pods.
+var preserveEmptyMapKeys = map[string]bool{
+	"emptyDir":          true,
+	"podSelector":       true,
+	"namespaceSelector": true,
+	"selector":          true,
+	"default":           true,
+}
+
+const labelSelectorDefinition = "io.k8s.apimachinery.pkg.apis.meta.v1.LabelSelector"
+
+// Int-or-string fields where a string of digits means the number. In
+// port fields, e.g. a Service's targetPort, strings are port names.
+var intOrStringKeys = map[string]bool{
+	"maxSurge":       true,
+	"maxUnavailable": true,
+	"minAvailable":   true,
+}
+
+// Canonical JSON of a manifest. Drops nulls and, for built-in kinds,
+// empty maps and normalizes resource quantities and int-or-string
+// fields, so that manifests only differing in representation compare
+// equal. Fields are identified by the OpenAPI schema, values of custom
+// resources and of fields not in the schema are kept as they are.
+func normalizeManifest(body []byte) ([]byte, error) {
+	body, err := manifestToJSON(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	err = d.Decode(&obj)
+	if err != nil {
+		return nil, err
+	}
+
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+
+	initOpenAPISchema()
+	openAPISchemaLock.RLock()
+	rs := openapi.SchemaForResourceType(kyaml.TypeMeta{APIVersion: apiVersion, Kind: kind})
+	n := normalizeValue(rs, "", "", obj)
+	openAPISchemaLock.RUnlock()
+
+	if n == nil {
+		n = map[string]interface{}{}
+	}
+
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	err = e.Encode(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// Normalizes v, rs is its schema or nil if unknown and
+// ref the name of the definition the schema references.
+func normalizeValue(rs *openapi.ResourceSchema, ref string, key string, v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{})
+		for k, e := range t {
+			var ers *openapi.ResourceSchema
+			if rs != nil {
+				ers = rs.Field(k)
+			}
+			eref := getSchemaRef(rs, k)
+
+			n := normalizeValue(ers, eref, k, e)
+			if n == nil {
+				continue
+			}
+
+			if m, ok := n.(map[string]interface{}); ok && len(m) == 0 && ers != nil && !preserveEmptyMap(eref, k) {
+				continue
+			}
+
+			out[k] = n
+		}
+		return out
+	case []interface{}:
+		var ers *openapi.ResourceSchema
+		if rs != nil {
+			ers = rs.Elements()
+		}
+		eref := getSchemaRef(rs, openapi.Elements)
+
+		out := make([]interface{}, len(t))
+		for i, e := range t {
+			out[i] = normalizeValue(ers, eref, key, e)
+		}
+		return out
+	}
+
+	switch {
+	case rs == nil:
+		return v
+	case ref == quantityDefinition:
+		return normalizeQuantity(v)
+	case rs.Schema.Format == "int-or-string" && intOrStringKeys[key]:
+		return normalizeIntOrString(v)
+	}
+
+	return v
+}
+
+func preserveEmptyMap(ref string, key string) bool {
+	return preserveEmptyMapKeys[key] || ref == labelSelectorDefinition
+}
+
+// Field and Elements resolve references, so get the name
+// of the referenced definition from the parent's schema.
+func getSchemaRef(rs *openapi.ResourceSchema, field string) string {
+	if rs == nil || rs.Schema == nil {
+		return ""
+	}
+
+	var s *spec.Schema
+	if field == openapi.Elements {
+		if rs.Schema.Items != nil {
+			s = rs.Schema.Items.Schema
+		}
+	} else if p, ok := rs.Schema.Properties[field]; ok {
+		s = &p
+	} else if rs.Schema.AdditionalProperties != nil {
+		s = rs.Schema.AdditionalProperties.Schema
+	}
+
+	if s == nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(s.Ref.String(), "#/definitions/")
+}
+
+func normalizeQuantity(v interface{}) interface{} {
+	switch v.(type) {
+	case string, json.Number:
+		q, err := k8sresource.ParseQuantity(fmt.Sprint(v))
+		if err != nil {
+			return v
+		}
+		return q.String()
+	}
+
+	return v
+}
+
+func normalizeIntOrString(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+
+	if _, err := strconv.Atoi(s); err != nil {
+		return v
+	}
+
+	return json.Number(s)
+}
+
 func setLastAppliedConfig(km *kManifest, gzipLastAppliedConfig bool) {
 	annotations := km.resource.GetAnnotations()
 	if len(annotations) == 0 {
 		annotations = make(map[string]string)
 	}
 
-	annotations[lastAppliedConfigAnnotation] = string(km.json)
+	lac := km.json
+	if n, err := normalizeManifest(km.json); err == nil {
+		lac = n
+	}
+
+	annotations[lastAppliedConfigAnnotation] = string(lac)
 
 	if gzipLastAppliedConfig {
 		needsGzip := false
@@ -45,7 +276,7 @@ func setLastAppliedConfig(km *kManifest, gzipLastAppliedConfig bool) {
 			var buf bytes.Buffer
 			zw := gzip.NewWriter(&buf)
 
-			_, err1 := zw.Write([]byte(km.json))
+			_, err1 := zw.Write(lac)
 
 			err2 := zw.Close()
 
@@ -3,6 +3,7 @@ package kustomize
 import (
 	"fmt"
 	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,6 +12,8 @@ import (
 
 func TestLastAppliedConfig(t *testing.T) {
 	srcJSON := "{\"apiVersion\": \"v1\", \"kind\": \"Namespace\", \"metadata\": {\"name\": \"test-unit\"}}"
+	// the last applied config is normalized
+	expJSON := "{\"apiVersion\":\"v1\",\"kind\":\"Namespace\",\"metadata\":{\"name\":\"test-unit\"}}"
 	km := &kManifest{}
 	err := km.load([]byte(srcJSON))
 	if err != nil {
@@ -25,8 +28,8 @@ func TestLastAppliedConfig(t *testing.T) {
 	}
 
 	lac := getLastAppliedConfig(km.resource, true)
-	if lac != srcJSON {
-		t.Errorf("TestLastAppliedConfig: incorrect annotation value, got: %s, want: %s.", srcJSON, lac)
+	if lac != expJSON {
+		t.Errorf("TestLastAppliedConfig: incorrect annotation value, got: %s, want: %s.", expJSON, lac)
 	}
 }
 
@@ -42,6 +45,7 @@ func randomDataHelper(n int) string {
 func TestLastAppliedConfigCompressed(t *testing.T) {
 	filler := randomDataHelper(256 * (1 << 10))
 	srcJSON := fmt.Sprintf("{\"apiVersion\": \"v1\", \"kind\": \"ConfigMap\", \"metadata\": {\"name\": \"test-unit\", \"namespace\": \"test-unit\"}, \"data\": {\"payload\": %q}}", filler)
+	expJSON := fmt.Sprintf("{\"apiVersion\":\"v1\",\"data\":{\"payload\":%q},\"kind\":\"ConfigMap\",\"metadata\":{\"name\":\"test-unit\",\"namespace\":\"test-unit\"}}", filler)
 
 	km := &kManifest{}
 	err := km.load([]byte(srcJSON))
@@ -61,14 +65,15 @@ func TestLastAppliedConfigCompressed(t *testing.T) {
 	}
 
 	lac := getLastAppliedConfig(km.resource, true)
-	if lac != srcJSON {
-		t.Errorf("TestLastAppliedConfigCompressed: incorrect annotation value, got: %s, want: %s.", srcJSON, lac)
+	if lac != expJSON {
+		t.Errorf("TestLastAppliedConfigCompressed: incorrect annotation value, got: %s, want: %s.", expJSON, lac)
 	}
 }
 
 func TestLastAppliedConfigCompressionDisabled(t *testing.T) {
 	filler := randomDataHelper(256 * (1 << 10))
 	srcJSON := fmt.Sprintf("{\"apiVersion\": \"v1\", \"kind\": \"ConfigMap\", \"metadata\": {\"name\": \"test-unit\", \"namespace\": \"test-unit\"}, \"data\": {\"payload\": %q}}", filler)
+	expJSON := fmt.Sprintf("{\"apiVersion\":\"v1\",\"data\":{\"payload\":%q},\"kind\":\"ConfigMap\",\"metadata\":{\"name\":\"test-unit\",\"namespace\":\"test-unit\"}}", filler)
 
 	km := &kManifest{}
 	err := km.load([]byte(srcJSON))
@@ -91,8 +96,8 @@ func TestLastAppliedConfigCompressionDisabled(t *testing.T) {
 	}
 
 	lac := getLastAppliedConfig(km.resource, false)
-	if lac != srcJSON {
-		t.Errorf("TestLastAppliedConfigCompressionDisabled: incorrect annotation value, got: %s, want: %s.", srcJSON, lac)
+	if lac != expJSON {
+		t.Errorf("TestLastAppliedConfigCompressionDisabled: incorrect annotation value, got: %s, want: %s.", expJSON, lac)
 	}
 }
 
@@ -147,3 +152,96 @@ func TestGetPatchMergePatch(t *testing.T) {
 	assert.Equal(t, `{"metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"test.example.com/v1alpha1\",\"kind\":\"Namespacedcrd\",\"metadata\":{\"name\":\"namespacedco\",\"namespace\":\"test-crd\"},\"spec\":{\"test-key\":\"test-value\"}}\n"}},"spec":{"test-key":"test-value"}}`, string(p), nil)
 	assert.Equal(t, types.MergePatchType, pt, nil)
 }
+
+func TestNormalizeManifest(t *testing.T) {
+	a := `{"kind": "Deployment", "apiVersion": "apps/v1", "metadata": {"name": "test", "creationTimestamp": null, "annotations": {}}, "spec": {"strategy": {"rollingUpdate": {"maxSurge": "1", "maxUnavailable": "25%"}}, "template": {"spec": {"containers": [{"name": "app", "ports": [{"containerPort": 8080}], "resources": {"limits": {"cpu": "1000m", "memory": "1024Mi"}, "requests": {}}}], "volumes": [{"name": "tmp", "emptyDir": {"sizeLimit": "1024Mi"}}]}}}}`
+	b := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  strategy:
+    rollingUpdate:
+      maxSurge: 1
+      maxUnavailable: 25%
+  template:
+    spec:
+      containers:
+      - name: app
+        ports:
+        - containerPort: 8080
+        resources:
+          limits:
+            cpu: 1
+            memory: 1Gi
+      volumes:
+      - name: tmp
+        emptyDir:
+          sizeLimit: 1Gi
+`
+
+	na, err := normalizeManifest([]byte(a))
+	assert.Equal(t, nil, err, nil)
+
+	nb, err := normalizeManifest([]byte(b))
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, string(na), string(nb), nil)
+	assert.Equal(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test"},"spec":{"strategy":{"rollingUpdate":{"maxSurge":1,"maxUnavailable":"25%"}},"template":{"spec":{"containers":[{"name":"app","ports":[{"containerPort":8080}],"resources":{"limits":{"cpu":"1","memory":"1Gi"}}}],"volumes":[{"emptyDir":{"sizeLimit":"1Gi"},"name":"tmp"}]}}}}`, string(na), nil)
+
+	// empty maps with a meaning are kept
+	n, err := normalizeManifest([]byte(`{"apiVersion": "networking.k8s.io/v1", "kind": "NetworkPolicy", "metadata": {"name": "test"}, "spec": {"podSelector": {}, "ingress": [{}]}}`))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"test"},"spec":{"ingress":[{}],"podSelector":{}}}`, string(n), nil)
+
+	// limit range quantities are normalized, other defaults are not
+	n, err = normalizeManifest([]byte(`{"apiVersion": "v1", "kind": "LimitRange", "metadata": {"name": "test"}, "spec": {"limits": [{"type": "Container", "default": {"cpu": "500m", "memory": "2048Mi"}}]}}`))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, `{"apiVersion":"v1","kind":"LimitRange","metadata":{"name":"test"},"spec":{"limits":[{"default":{"cpu":"500m","memory":"2Gi"},"type":"Container"}]}}`, string(n), nil)
+
+	n, err = normalizeManifest([]byte(`{"apiVersion": "example.com/v1", "kind": "Config", "metadata": {"name": "test"}, "spec": {"default": {"value": "1000m"}}}`))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, `{"apiVersion":"example.com/v1","kind":"Config","metadata":{"name":"test"},"spec":{"default":{"value":"1000m"}}}`, string(n), nil)
+
+	// custom resources are not normalized by key name
+	n, err = normalizeManifest([]byte(`{"apiVersion": "example.com/v1", "kind": "Pool", "metadata": {"name": "test", "creationTimestamp": null}, "spec": {"limits": {"connections": 1000}, "options": {}}}`))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, `{"apiVersion":"example.com/v1","kind":"Pool","metadata":{"name":"test"},"spec":{"limits":{"connections":1000},"options":{}}}`, string(n), nil)
+
+	// empty label selectors match all pods and are kept
+	n, err = normalizeManifest([]byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "test"}, "spec": {"topologySpreadConstraints": [{"maxSkew": 1, "topologyKey": "zone", "whenUnsatisfiable": "DoNotSchedule", "labelSelector": {}}], "containers": [{"name": "app", "image": "nginx", "resources": {}}]}}`))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"test"},"spec":{"containers":[{"image":"nginx","name":"app"}],"topologySpreadConstraints":[{"labelSelector":{},"maxSkew":1,"topologyKey":"zone","whenUnsatisfiable":"DoNotSchedule"}]}}`, string(n), nil)
+
+	// strings in port fields are port names, they are kept
+	n, err = normalizeManifest([]byte(`{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "test"}, "spec": {"ports": [{"port": 80, "targetPort": "8080"}]}}`))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, `{"apiVersion":"v1","kind":"Service","metadata":{"name":"test"},"spec":{"ports":[{"port":80,"targetPort":"8080"}]}}`, string(n), nil)
+
+	assert.Equal(t, true, manifestDiffSuppressFunc("manifest", a, b, nil), nil)
+	assert.Equal(t, false, manifestDiffSuppressFunc("manifest", a, strings.Replace(b, "cpu: 1", "cpu: 2", 1), nil), nil)
+	assert.Equal(t, false, manifestDiffSuppressFunc("manifest", "", b, nil), nil)
+}
+
+func TestManifestToJSON(t *testing.T) {
+	srcJSON := "{\"apiVersion\": \"v1\", \"kind\": \"Namespace\", \"metadata\": {\"name\": \"test-unit\"}}"
+	j, err := manifestToJSON([]byte(srcJSON))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, srcJSON, string(j), nil)
+
+	srcYAML := "---\n# test\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: test-unit\n---\n"
+	j, err = manifestToJSON([]byte(srcYAML))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"test-unit"}}`, string(j), nil)
+
+	_, err = manifestToJSON([]byte(srcYAML + srcYAML))
+	assert.NotEqual(t, nil, err, nil)
+
+	_, err = manifestToJSON([]byte("---\n"))
+	assert.NotEqual(t, nil, err, nil)
+
+	km := &kManifest{}
+	err = km.load([]byte(srcYAML))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "test-unit", km.name(), nil)
+}